@@ -0,0 +1,157 @@
+package progressbar
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Checkpoint is the progress of a run saved by a CheckpointStore
+type Checkpoint struct {
+	// Completed holds the ids of the finished tasks, unnamed tasks without an
+	// id are recorded by their index as "#<index>"
+	Completed []string
+	State     progressbar.State
+}
+
+// CheckpointStore persists checkpoints so an interrupted run can be resumed
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or nil if there is none
+	Load() (*Checkpoint, error)
+	Save(checkpoint *Checkpoint) error
+	// Clear removes the saved checkpoint once a run completed
+	Clear() error
+}
+
+// FileCheckpoint is a CheckpointStore keeping the checkpoint as JSON file
+type FileCheckpoint struct {
+	path string
+}
+
+// NewFileCheckpoint returns a store saving the checkpoint to path
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+func (f *FileCheckpoint) Load() (*Checkpoint, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint to a temporary file first, so a crash while
+// saving keeps the previous checkpoint intact
+func (f *FileCheckpoint) Save(checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *FileCheckpoint) Clear() error {
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// defaultCheckpointInterval is how often a run saves its checkpoint unless
+// CheckpointInterval sets otherwise
+const defaultCheckpointInterval = time.Second
+
+// Checkpoint makes RunCollect and AutoRun save the finished tasks to store
+// while they run, see CheckpointInterval, and once more when the run ends.
+// A run finding a checkpoint skips the tasks finished before and starts
+// with the bar filled by their weights. The checkpoint is cleared once all
+// tasks finished successfully.
+func (p *ProgressBar) Checkpoint(store CheckpointStore) *ProgressBar {
+	p.checkpoint = store
+	return p
+}
+
+// CheckpointInterval sets how often the checkpoint is saved during a run,
+// one second by default. Tasks finished since the last save are lost if the
+// process dies before the next one. An interval of 0 saves after every task.
+func (p *ProgressBar) CheckpointInterval(interval time.Duration) *ProgressBar {
+	p.saveEvery = &interval
+	return p
+}
+
+// resume loads the checkpoint of the run, marks the finished tasks and
+// returns their total weight
+func (r *run) resume() (int, error) {
+	checkpoint, err := r.checkpoint.Load()
+	if err != nil || checkpoint == nil {
+		return 0, err
+	}
+	finished := make(map[string]bool, len(checkpoint.Completed))
+	for _, key := range checkpoint.Completed {
+		finished[key] = true
+	}
+	r.completed = checkpoint.Completed
+	r.resumed = make(map[int]bool)
+	weight := 0
+	for idx, task := range r.tasks {
+		if finished[r.graph.checkpointKey(idx)] {
+			r.resumed[idx] = true
+			weight += task.weightOrDefault()
+		}
+	}
+	return weight, nil
+}
+
+// save records the task at idx as finished, writing the checkpoint once the
+// interval since the last write elapsed
+func (r *run) save(idx int) {
+	r.completed = append(r.completed, r.graph.checkpointKey(idx))
+	r.unsaved = true
+	interval := defaultCheckpointInterval
+	if r.p.saveEvery != nil {
+		interval = *r.p.saveEvery
+	}
+	if time.Since(r.saved) >= interval {
+		r.flush()
+	}
+}
+
+// flush writes the finished tasks to the checkpoint store if any were
+// recorded since the last write
+func (r *run) flush() {
+	if r.checkpoint == nil || !r.unsaved {
+		return
+	}
+	checkpoint := &Checkpoint{Completed: r.completed}
+	if r.p.bar.Load() != nil {
+		checkpoint.State = r.p.State()
+	}
+	r.saved, r.unsaved = time.Now(), false
+	if err := r.checkpoint.Save(checkpoint); err != nil {
+		r.abort(err)
+	}
+}
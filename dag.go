@@ -0,0 +1,169 @@
+package progressbar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// taskGraph holds the dependencies between the tasks of a run
+type taskGraph struct {
+	// dependents lists for every task the tasks depending on it
+	dependents [][]int
+	// pending counts the unfinished dependencies of every task
+	pending []int
+	// blocked is the id of a failed dependency of every task, "" if none
+	blocked []string
+	// keys is the unique id or name of every task, "" if it has none
+	keys  []string
+	names []string
+}
+
+// buildGraph validates the dependencies of the tasks, reporting duplicate
+// ids, unknown or ambiguous dependencies and cycles. Dependencies refer to
+// the id of a task, or to its name if no task has that id. Names only need
+// to be unique if a dependency refers to them.
+func (p *ProgressBar) buildGraph() (*taskGraph, error) {
+	g := &taskGraph{
+		dependents: make([][]int, len(p.tasks)),
+		pending:    make([]int, len(p.tasks)),
+		blocked:    make([]string, len(p.tasks)),
+		keys:       make([]string, len(p.tasks)),
+		names:      make([]string, len(p.tasks)),
+	}
+	ids := make(map[string]int, len(p.tasks))
+	names := make(map[string][]int, len(p.tasks))
+	for i, task := range p.tasks {
+		g.names[i] = task.name
+		if task.name != "" {
+			names[task.name] = append(names[task.name], i)
+		}
+		if task.id == "" {
+			continue
+		}
+		if _, ok := ids[task.id]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateTaskID, task.id)
+		}
+		ids[task.id] = i
+		g.keys[i] = task.id
+	}
+	// 名称唯一且未被 id 占用时才作为任务的标识
+	for name, tasks := range names {
+		if _, ok := ids[name]; !ok && len(tasks) == 1 && g.keys[tasks[0]] == "" {
+			g.keys[tasks[0]] = name
+		}
+	}
+	resolve := func(i int, dep string) (int, error) {
+		if j, ok := ids[dep]; ok {
+			return j, nil
+		}
+		switch tasks := names[dep]; len(tasks) {
+		case 0:
+			return 0, fmt.Errorf("%w: %s requires %s", ErrMissingDependency, g.describe(i), dep)
+		case 1:
+			return tasks[0], nil
+		default:
+			return 0, fmt.Errorf("%w: %s requires %s, the name of %d tasks", ErrDuplicateTaskID, g.describe(i), dep, len(tasks))
+		}
+	}
+	for i, task := range p.tasks {
+		for _, dep := range task.dependsOn {
+			j, err := resolve(i, dep)
+			if err != nil {
+				return nil, err
+			}
+			g.dependents[j] = append(g.dependents[j], i)
+			g.pending[i]++
+		}
+	}
+	if err := g.checkCycles(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// checkCycles reports the tasks that are part of or depend on a cycle
+func (g *taskGraph) checkCycles() error {
+	order := g.order()
+	if len(order) == len(g.pending) {
+		return nil
+	}
+	sorted := make([]bool, len(g.pending))
+	for _, idx := range order {
+		sorted[idx] = true
+	}
+	cycle := make([]string, 0, len(g.pending)-len(order))
+	for i := range g.pending {
+		if !sorted[i] {
+			cycle = append(cycle, g.describe(i))
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, ", "))
+}
+
+// order returns the tasks in the order a single worker runs them, leaving
+// out tasks that can never run because of a cycle
+func (g *taskGraph) order() []int {
+	pending := append([]int(nil), g.pending...)
+	queue := g.roots()
+	order := make([]int, 0, len(pending))
+	for len(queue) > 0 {
+		idx := queue[0]
+		queue = queue[1:]
+		order = append(order, idx)
+		for _, dep := range g.dependents[idx] {
+			pending[dep]--
+			if pending[dep] == 0 {
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return order
+}
+
+// roots returns the tasks without dependencies in registration order
+func (g *taskGraph) roots() []int {
+	roots := make([]int, 0, len(g.pending))
+	for i, n := range g.pending {
+		if n == 0 {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// release marks the task at idx as finished and returns the dependents
+// whose dependencies are all finished now
+func (g *taskGraph) release(idx int, failed bool) []int {
+	var ready []int
+	for _, dep := range g.dependents[idx] {
+		if failed && g.blocked[dep] == "" {
+			g.blocked[dep] = g.describe(idx)
+		}
+		g.pending[dep]--
+		if g.pending[dep] == 0 {
+			ready = append(ready, dep)
+		}
+	}
+	return ready
+}
+
+// describe returns the key of the task at idx for messages, falling back to
+// its name and then to its index
+func (g *taskGraph) describe(idx int) string {
+	if g.keys[idx] != "" {
+		return g.keys[idx]
+	}
+	if g.names[idx] != "" {
+		return g.names[idx]
+	}
+	return fmt.Sprintf("#%d", idx)
+}
+
+// checkpointKey returns the identifier of the task at idx in checkpoints,
+// its index if it has no unique key
+func (g *taskGraph) checkpointKey(idx int) string {
+	if g.keys[idx] != "" {
+		return g.keys[idx]
+	}
+	return fmt.Sprintf("#%d", idx)
+}
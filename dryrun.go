@@ -0,0 +1,73 @@
+package progressbar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DryRun previews what AutoRun would do without calling any task. It
+// validates the dependencies and the parameters of every task, writes the
+// planned order with names and weights to w and, if the bar was created,
+// shows the simulated run on a throwaway copy of it, so the bar itself is
+// left untouched. Invalid tasks are reported as *TaskError wrapping
+// ErrInvalidTask.
+func (p *ProgressBar) DryRun(w io.Writer) error {
+	if p.Error() != nil {
+		return p.Error()
+	}
+	graph, err := p.checkTasks()
+	if err != nil {
+		return err
+	}
+
+	order := graph.order()
+	for step, idx := range order {
+		task := p.tasks[idx]
+		line := fmt.Sprintf("%d. %s (weight %d)", step+1, graph.describe(idx), task.weightOrDefault())
+		if len(task.dependsOn) > 0 {
+			line += " after " + strings.Join(task.dependsOn, ", ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	// 未创建进度条时只输出计划
+	if p.bar.Load() == nil {
+		return nil
+	}
+	// 用同样配置的临时进度条模拟, 不影响之后真正的运行
+	opts := p.opts.Clone()
+	opts.completion = nil
+	sim := newBar(p.maxSteps(), opts)
+	for _, idx := range order {
+		if name := p.tasks[idx].name; name != "" {
+			sim.Describe(name)
+		}
+		if err := sim.Add(p.tasks[idx].weightOrDefault()); err != nil {
+			return err
+		}
+	}
+	return sim.Exit()
+}
+
+// checkTasks validates the dependencies and the parameters of every task
+// without calling any of them
+func (p *ProgressBar) checkTasks() (*taskGraph, error) {
+	graph, err := p.buildGraph()
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for idx, task := range p.tasks {
+		if err := task.validate(); err != nil {
+			errs = append(errs, &TaskError{Index: idx, Name: task.name, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return graph, nil
+}
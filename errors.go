@@ -1,8 +1,105 @@
 package progressbar
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var (
 	ErrNilBar       = errors.New("go-progressbar:progressbar is nil")
 	ErrInvalidTotal = errors.New("go-progressbar:total must be greater than 0")
+	ErrTaskTimeout  = errors.New("go-progressbar:task timed out")
+	ErrInvalidTask  = errors.New("go-progressbar:invalid task")
+	ErrInterrupted  = errors.New("go-progressbar:run interrupted by signal")
+
+	ErrConflictingOptions = errors.New("go-progressbar:conflicting options")
+
+	ErrDuplicateTaskID   = errors.New("go-progressbar:duplicate task id")
+	ErrMissingDependency = errors.New("go-progressbar:task depends on an unknown task")
+	ErrDependencyCycle   = errors.New("go-progressbar:task dependencies form a cycle")
+	ErrDependencyFailed  = errors.New("go-progressbar:task skipped because a dependency failed")
+
+	// ErrSkipTask is returned by a task function to mark the task as skipped
+	ErrSkipTask = errors.New("go-progressbar:task skipped")
 )
+
+// TaskError records the failure of a single task executed by AutoRun
+type TaskError struct {
+	// Index is the position of the task in the order it was registered
+	Index int
+	// Name is the name of the task, empty for unnamed tasks
+	Name string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("go-progressbar:task %d (%s): %v", e.Index, e.Name, e.Err)
+	}
+	return fmt.Sprintf("go-progressbar:task %d: %v", e.Index, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned for a task that panicked instead of returning
+type PanicError struct {
+	// Value is the value passed to panic
+	Value any
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("go-progressbar:task panicked: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// OpError records a failed call on a ProgressBar
+type OpError struct {
+	// Op is the method that failed, such as "Prefix" or "Set"
+	Op string
+	// Time is when the failure was recorded
+	Time time.Time
+	Err  error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("go-progressbar:%s: %v", e.Op, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// BarError is returned by ProgressBar.Error and holds every recorded
+// failure, oldest first. errors.Is and errors.As look at all of them.
+type BarError struct {
+	Errors []*OpError
+}
+
+func (e *BarError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *BarError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+package progressbar
+
+import "time"
+
+// TaskEvent describes a task passed to the task hooks
+type TaskEvent struct {
+	// Index is the position of the task in the order it was registered
+	Index int
+	// ID is the id of the task, or its name if it has none
+	ID   string
+	Name string
+	// Attempt is the number of the attempt the event belongs to, starting at 1
+	Attempt int
+	// Elapsed is the time spent on the task so far
+	Elapsed time.Duration
+	// Err is the error of the task, or of the previous attempt for retries
+	Err error
+}
+
+// RunEvent describes a finished run passed to the OnRunComplete hook
+type RunEvent struct {
+	Tasks int
+	Summary
+	Elapsed time.Duration
+	// Waited is the time tasks spent waiting for the rate limiter
+	Waited time.Duration
+	// Err is the error returned by the run
+	Err error
+}
+
+// hooks holds the callbacks registered on a ProgressBar
+type hooks struct {
+	taskStart   func(TaskEvent)
+	taskSuccess func(TaskEvent)
+	taskFailure func(TaskEvent)
+	taskSkip    func(TaskEvent)
+	retry       func(TaskEvent)
+	runComplete func(RunEvent)
+}
+
+// OnTaskStart registers fn to be called before a task runs for the first time.
+// Hooks are called from the worker goroutines and must be safe for concurrent use.
+func (p *ProgressBar) OnTaskStart(fn func(TaskEvent)) *ProgressBar {
+	p.hooks.taskStart = fn
+	return p
+}
+
+// OnTaskSuccess registers fn to be called after a task succeeded
+func (p *ProgressBar) OnTaskSuccess(fn func(TaskEvent)) *ProgressBar {
+	p.hooks.taskSuccess = fn
+	return p
+}
+
+// OnTaskFailure registers fn to be called after a task failed for good,
+// including tasks skipped because a dependency failed
+func (p *ProgressBar) OnTaskFailure(fn func(TaskEvent)) *ProgressBar {
+	p.hooks.taskFailure = fn
+	return p
+}
+
+// OnTaskSkip registers fn to be called for a task that was skipped, either by
+// its SkipIf predicate or by returning ErrSkipTask. It closes the OnTaskStart
+// event of tasks that returned ErrSkipTask.
+func (p *ProgressBar) OnTaskSkip(fn func(TaskEvent)) *ProgressBar {
+	p.hooks.taskSkip = fn
+	return p
+}
+
+// OnRetry registers fn to be called before a task is attempted again
+func (p *ProgressBar) OnRetry(fn func(TaskEvent)) *ProgressBar {
+	p.hooks.retry = fn
+	return p
+}
+
+// OnRunComplete registers fn to be called once a run finished
+func (p *ProgressBar) OnRunComplete(fn func(RunEvent)) *ProgressBar {
+	p.hooks.runComplete = fn
+	return p
+}
+
+// call invokes hook with event if it is set
+func (h hooks) call(hook func(TaskEvent), event TaskEvent) {
+	if hook != nil {
+		hook(event)
+	}
+}
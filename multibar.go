@@ -0,0 +1,208 @@
+package progressbar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ansiCodes are the escape sequences the underlying bar uses to clear a line
+var ansiCodes = strings.NewReplacer("\033[2K", "", "\033[K", "")
+
+// MultiBar renders several bars as a block of lines, one line per bar,
+// redrawing the block in place with cursor movement. It is safe for
+// concurrent use.
+type MultiBar struct {
+	w              io.Writer
+	removeFinished bool
+	mu             sync.Mutex // guards entries and lines
+	entries        []*barLine
+	lines          int
+	refresh        chan struct{}
+	done           chan struct{}
+	stopped        chan struct{}
+	closeOnce      sync.Once
+}
+
+// barLine captures the output of one bar of a MultiBar
+type barLine struct {
+	m     *MultiBar
+	bar   *ProgressBar
+	depth int
+	mu    sync.Mutex
+	line  string
+}
+
+// NewMultiBar creates a MultiBar drawing to w, os.Stdout if w is nil.
+// Close must be called once all bars are done.
+func NewMultiBar(w io.Writer) *MultiBar {
+	if w == nil {
+		w = os.Stdout
+	}
+	m := &MultiBar{
+		w:       w,
+		refresh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go m.loop()
+	return m
+}
+
+// RemoveOnFinish removes finished bars from the block instead of keeping
+// them pinned at their final state
+func (m *MultiBar) RemoveOnFinish() *MultiBar {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeFinished = true
+	return m
+}
+
+// Add creates bar with its output redirected into the block and appends it
+// as the last line. It may be called while other bars are running. A bar
+// that fails to be created (see Error) is not shown.
+func (m *MultiBar) Add(bar *ProgressBar) *ProgressBar {
+	return m.add(bar, nil)
+}
+
+// add creates bar inside the block, below the last descendant of parent if
+// parent is shown
+func (m *MultiBar) add(bar, parent *ProgressBar) *ProgressBar {
+	entry := &barLine{m: m, bar: bar}
+	bar.opts.writer = entry
+	if bar.Create(); bar.bar.Load() == nil {
+		return bar
+	}
+	bar.multi = m
+	m.mu.Lock()
+	at := len(m.entries)
+	for i, e := range m.entries {
+		if parent == nil || e.bar != parent {
+			continue
+		}
+		entry.depth = e.depth + 1
+		at = i + 1
+		for at < len(m.entries) && m.entries[at].depth >= entry.depth {
+			at++
+		}
+		break
+	}
+	m.entries = slices.Insert(m.entries, at, entry)
+	m.mu.Unlock()
+	m.notify()
+	return bar
+}
+
+// Remove takes bar out of the block, its line disappears on the next render
+func (m *MultiBar) Remove(bar *ProgressBar) {
+	m.mu.Lock()
+	m.entries = slices.DeleteFunc(m.entries, func(entry *barLine) bool {
+		return entry.bar == bar
+	})
+	m.mu.Unlock()
+	m.notify()
+}
+
+// Bars returns the bars currently shown, top to bottom
+func (m *MultiBar) Bars() []*ProgressBar {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bars := make([]*ProgressBar, len(m.entries))
+	for i, entry := range m.entries {
+		bars[i] = entry.bar
+	}
+	return bars
+}
+
+// Render redraws the block at once. Bars trigger a redraw by themselves
+// whenever they change, so calling it is rarely needed.
+func (m *MultiBar) Render() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.removeFinished {
+		m.entries = slices.DeleteFunc(m.entries, func(entry *barLine) bool {
+			// 未创建的 bar 不调用会记录错误的方法
+			return entry.bar.bar.Load() != nil && entry.bar.IsFinished()
+		})
+	}
+
+	var b strings.Builder
+	if m.lines > 0 {
+		// 回到上次绘制的第一行
+		fmt.Fprintf(&b, "\033[%dA", m.lines)
+	}
+	for _, entry := range m.entries {
+		b.WriteString("\r\033[2K")
+		b.WriteString(entry.text())
+		b.WriteString("\n")
+	}
+	if extra := m.lines - len(m.entries); extra > 0 {
+		// 清除已移除的 bar 留下的行
+		b.WriteString(strings.Repeat("\r\033[2K\n", extra))
+		fmt.Fprintf(&b, "\033[%dA", extra)
+	}
+	m.lines = len(m.entries)
+	_, err := io.WriteString(m.w, b.String())
+	return err
+}
+
+// Close stops redrawing after rendering the block a last time. The cursor
+// is left below the block.
+func (m *MultiBar) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+		<-m.stopped
+	})
+	return m.Render()
+}
+
+// loop redraws the block whenever a bar changed until Close is called
+func (m *MultiBar) loop() {
+	defer close(m.stopped)
+	for {
+		select {
+		case <-m.refresh:
+			_ = m.Render()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// notify schedules a redraw of the block
+func (m *MultiBar) notify() {
+	select {
+	case m.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Write records the last visible line written by the underlying bar. It is
+// called with the lock of the underlying bar held, so the redraw happens
+// in the loop of the MultiBar.
+func (l *barLine) Write(data []byte) (int, error) {
+	segments := strings.FieldsFunc(ansiCodes.Replace(string(data)), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	for i := len(segments) - 1; i >= 0; i-- {
+		// 跳过清行时写入的空白
+		if line := strings.TrimRight(segments[i], " "); line != "" {
+			l.mu.Lock()
+			l.line = line
+			l.mu.Unlock()
+			l.m.notify()
+			break
+		}
+	}
+	return len(data), nil
+}
+
+// text returns the last line of the bar, indented by its depth
+func (l *barLine) text() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Repeat("  ", l.depth) + l.line
+}
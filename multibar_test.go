@@ -0,0 +1,83 @@
+package progressbar
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMultiBar(t *testing.T) {
+	out := &syncBuffer{}
+	multi := NewMultiBar(out).RemoveOnFinish()
+	first := multi.Add(NewProgressBar().Total(10))
+	second := multi.Add(NewProgressBar().Total(10))
+	third := multi.Add(NewProgressBar().Total(10))
+	first.Describe("first")
+	second.Describe("second")
+	third.Describe("third")
+
+	var wg sync.WaitGroup
+	for _, bar := range []*ProgressBar{first, second, third} {
+		wg.Add(1)
+		go func(bar *ProgressBar) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				_ = bar.Add(1)
+			}
+		}(bar)
+	}
+	wg.Wait()
+	for i := 0; i < 2; i++ {
+		if err := multi.Render(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"first", "second", "third"} {
+		if !strings.Contains(out.String(), name) {
+			t.Errorf("expected %q to be rendered, got %q", name, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "\033[3A") {
+		t.Error("expected the block to be redrawn in place")
+	}
+
+	multi.Remove(second)
+	_ = first.Finish()
+	if err := multi.Render(); err != nil {
+		t.Fatal(err)
+	}
+	if bars := multi.Bars(); len(bars) != 1 || bars[0] != third {
+		t.Errorf("expected only the running bar to remain, got %d bars", len(bars))
+	}
+	if err := multi.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before := len(out.String())
+	_ = multi.Render()
+	last := out.String()[before:]
+	if strings.Contains(last, "first") || strings.Contains(last, "second") || !strings.Contains(last, "third") {
+		t.Errorf("expected the last redraw to show only the third bar, got %q", last)
+	}
+
+	pinned := NewMultiBar(io.Discard)
+	done := pinned.Add(NewProgressBar().Total(1))
+	_ = done.Finish()
+	_ = pinned.Close()
+	if len(pinned.Bars()) != 1 {
+		t.Error("expected finished bars to stay pinned by default")
+	}
+
+	invalid := NewMultiBar(io.Discard).RemoveOnFinish()
+	broken := invalid.Add(NewProgressBar().Total(0))
+	for i := 0; i < 5; i++ {
+		_ = invalid.Render()
+	}
+	_ = invalid.Close()
+	if len(invalid.Bars()) != 0 {
+		t.Error("expected a bar that failed to be created not to be shown")
+	}
+	if errs := broken.Errors(); len(errs) != 1 || errs[0].Op != "Create" {
+		t.Errorf("expected only the Create error to be recorded, got %v", broken.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package progressbar
+
+// Child creates a bar of total steps with the options of p that belongs to
+// p. The completion callback of p stays with p, and so do its spinner
+// settings unless the child is a spinner itself. When the child finishes it
+// advances p by one step, or by its total with WeightChildrenByTotal.
+// Unless p has an explicit Total, the maximum of p grows with every child.
+// Children of a bar shown in a MultiBar are drawn indented below it.
+func (p *ProgressBar) Child(total int) *ProgressBar {
+	opts := p.opts.Clone()
+	opts.completion = nil
+	if total != -1 {
+		opts.spinnerType, opts.spinnerCustom, opts.spinnerChangeInterval = nil, nil, nil
+	}
+	child := NewProgressBar().Total(total).Options(opts)
+	child.parent = p
+	p.mu.Lock()
+	p.children = append(p.children, child)
+	p.mu.Unlock()
+	if p.multi != nil {
+		p.multi.add(child, p)
+	} else {
+		child.Create()
+	}
+	if !p.totalSet {
+		p.changeMax(p.maxSteps())
+	}
+	return child
+}
+
+// WeightChildrenByTotal makes each finished child advance p by its total
+// instead of by one step
+func (p *ProgressBar) WeightChildrenByTotal() *ProgressBar {
+	p.byTotal = true
+	return p
+}
+
+// Children returns the child bars of p in creation order
+func (p *ProgressBar) Children() []*ProgressBar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*ProgressBar(nil), p.children...)
+}
+
+// childSteps returns how many steps all children of p add up to
+func (p *ProgressBar) childSteps() int {
+	sum := 0
+	for _, child := range p.Children() {
+		sum += p.childStep(child)
+	}
+	return sum
+}
+
+// childStep returns how far child advances p when it finishes, a spinner
+// child counts as one step
+func (p *ProgressBar) childStep(child *ProgressBar) int {
+	if p.byTotal && child.total > 1 {
+		return child.total
+	}
+	return 1
+}
+
+// rollUp advances the parent once p finished, at most once per bar
+func (p *ProgressBar) rollUp() {
+	if p.parent == nil || p.parent.bar.Load() == nil || !p.IsFinished() {
+		return
+	}
+	if p.rolledUp.CompareAndSwap(false, true) {
+		_ = p.parent.Add(p.parent.childStep(p))
+	}
+}
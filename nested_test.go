@@ -0,0 +1,91 @@
+package progressbar
+
+import (
+	"io"
+	"testing"
+)
+
+func TestChildBars(t *testing.T) {
+	parent := NewProgressBar().Options(quietOptions()).Create()
+	first := parent.Child(3)
+	second := parent.Child(5)
+	if parent.State().Max != 2 {
+		t.Errorf("expected the parent to count its children, got max %d", parent.State().Max)
+	}
+	for i := 0; i < 3; i++ {
+		_ = first.Next()
+	}
+	_ = first.Set(3)
+	if got := parent.State().CurrentNum; got != 1 {
+		t.Errorf("expected a finished child to advance the parent once, got %d", got)
+	}
+	_ = second.Finish()
+	if !parent.IsFinished() {
+		t.Error("expected the parent to finish with its last child")
+	}
+
+	weighted := NewProgressBar().Options(quietOptions()).WeightChildrenByTotal().Create()
+	small := weighted.Child(2)
+	weighted.Child(8)
+	_ = small.Finish()
+	if state := weighted.State(); state.Max != 10 || state.CurrentNum != 2 {
+		t.Errorf("expected 2/10 for weighted children, got %d/%d", state.CurrentNum, state.Max)
+	}
+
+	multi := NewMultiBar(io.Discard)
+	root := multi.Add(NewProgressBar().Total(2))
+	other := multi.Add(NewProgressBar().Total(1))
+	child := root.Child(1)
+	grandchild := child.Child(1)
+	next := root.Child(1)
+	_ = multi.Close()
+	want := []*ProgressBar{root, child, grandchild, next, other}
+	got := multi.Bars()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d bars, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected children below their parent, bar %d is out of place", i)
+		}
+	}
+
+	withTasks := NewProgressBar().Options(quietOptions()).Tasks(NewProgressTask(func() {})).Create()
+	download := withTasks.Child(4)
+	if err := withTasks.AutoRun(); err != nil {
+		t.Fatal(err)
+	}
+	if state := withTasks.State(); state.Max != 2 || withTasks.IsFinished() {
+		t.Errorf("expected a run to keep counting the children, got %d/%d", state.CurrentNum, state.Max)
+	}
+	_ = download.Finish()
+	if !withTasks.IsFinished() {
+		t.Error("expected the bar to finish with its child")
+	}
+
+	var completed int
+	withCompletion := NewProgressBar().Total(2).Options(quietOptions().Completion(func() { completed++ })).Create()
+	_ = withCompletion.Child(1).Finish()
+	if completed != 0 {
+		t.Errorf("expected the child not to run the completion of its parent, ran %d times", completed)
+	}
+	_ = withCompletion.Add(1)
+	if completed != 1 {
+		t.Errorf("expected the completion to run once for the parent, ran %d times", completed)
+	}
+
+	spinner := NewProgressBar().Total(-1).Options(quietOptions().SpinnerType(9)).Create()
+	if child := spinner.Child(5); child.Error() != nil {
+		t.Errorf("expected a child of a spinner to drop its spinner settings, got %v", child.Error())
+	}
+	if child := spinner.AddBar(); child.Error() != nil {
+		t.Errorf("expected a spinner child to keep the spinner settings, got %v", child.Error())
+	}
+
+	spinners := NewProgressBar().Options(quietOptions()).WeightChildrenByTotal().Create()
+	spinners.Child(-1)
+	spinners.Child(3)
+	if max := spinners.State().Max; max != 4 {
+		t.Errorf("expected a spinner child to count as one step, got max %d", max)
+	}
+}
@@ -2,145 +2,172 @@ package progressbar
 
 import (
 	"io"
+	"slices"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 )
 
+// Options holds the settings a bar is created with. Unset settings keep the
+// default of the underlying library, they are only translated to it when
+// the bar is created.
 type Options struct {
-	options []progressbar.Option
+	writer                io.Writer
+	width                 *int
+	fullWidth             bool
+	showTotalBytes        *bool
+	spinnerChangeInterval *time.Duration
+	spinnerType           *int
+	spinnerCustom         []string
+	theme                 *progressbar.Theme
+	invisible             bool
+	renderBlankState      *bool
+	throttle              *time.Duration
+	showCount             bool
+	showIts               bool
+	completion            func()
+	colorCodes            bool
+	elapsedTime           *bool
+	noPredictTime         bool
+	elapsedTimeOnFinish   bool
+	itsString             *string
+	clearOnFinish         bool
+	showBytes             bool
+	ansiCodes             bool
+	iecUnits              bool
+	descriptionAtLineEnd  bool
+	maxDetailRow          *int
 }
 
 func ProgressOptions() *Options {
-	return &Options{
-		options: make([]progressbar.Option, 0),
-	}
+	return &Options{}
 }
 
 func DefaultOptions() *Options {
-	return &Options{
-		options: make([]progressbar.Option, 0),
-	}
+	return &Options{}
 }
 
 func (p *Options) Writer(w io.Writer) *Options {
-	p.options = append(p.options, progressbar.OptionSetWriter(w))
+	p.writer = w
 	return p
 }
 
 // Width sets the width of the bar
 func (p *Options) Width(width int) *Options {
-	p.options = append(p.options, progressbar.OptionSetWidth(width))
+	p.width = &width
 	return p
 }
 
 // FullWidth sets the bar to be full width
 func (p *Options) FullWidth() *Options {
-	p.options = append(p.options, progressbar.OptionFullWidth())
+	p.fullWidth = true
 	return p
 }
 
 func (p *Options) DisShowTotalBytes(val bool) *Options {
-	p.options = append(p.options, progressbar.OptionShowTotalBytes(val))
+	p.showTotalBytes = &val
 	return p
 }
 
 func (p *Options) SpinnerChangeInterval(interval time.Duration) *Options {
-	p.options = append(p.options, progressbar.OptionSetSpinnerChangeInterval(interval))
+	p.spinnerChangeInterval = &interval
 	return p
 }
 
-// SpinnerType sets the type of spinner used for indeterminate bars
+// SpinnerType sets the type of spinner used for indeterminate bars,
+// replacing a custom spinner
 func (p *Options) SpinnerType(spinnerType int) *Options {
-	p.options = append(p.options, progressbar.OptionSpinnerType(spinnerType))
+	p.spinnerType = &spinnerType
+	p.spinnerCustom = nil
 	return p
 }
 
 // SpinnerCustom sets the spinner used for indeterminate bars to the passed
-// slice of string
+// slice of string, replacing the spinner type
 func (p *Options) SpinnerCustom(spinner ...string) *Options {
-	p.options = append(p.options, progressbar.OptionSpinnerCustom(spinner))
+	p.spinnerCustom = slices.Clone(spinner)
+	p.spinnerType = nil
 	return p
 }
 
 // Theme sets the elements the bar is constructed with.
 // There are two pre-defined themes you can use: ThemeASCII and ThemeUnicode.
 func (p *Options) Theme(t progressbar.Theme) *Options {
-	p.options = append(p.options, progressbar.OptionSetTheme(t))
+	p.theme = &t
 	return p
 }
 
 // DisEnableVisibility enable the visibility
 func (p *Options) DisEnableVisibility() *Options {
-	p.options = append(p.options, progressbar.OptionSetVisibility(false))
+	p.invisible = true
 	return p
 }
 
 func (p *Options) RenderBlankState(r bool) *Options {
-	p.options = append(p.options, progressbar.OptionSetRenderBlankState(r))
+	p.renderBlankState = &r
 	return p
 }
 
 func (p *Options) Throttle(duration time.Duration) *Options {
-	p.options = append(p.options, progressbar.OptionThrottle(duration))
+	p.throttle = &duration
 	return p
 }
 
 // EnableShowCount will also print current count out of total
 func (p *Options) EnableShowCount() *Options {
-	p.options = append(p.options, progressbar.OptionShowCount())
+	p.showCount = true
 	return p
 }
 
 // EnableShowIts will also print the iterations/second
 func (p *Options) EnableShowIts() *Options {
-	p.options = append(p.options, progressbar.OptionShowIts())
+	p.showIts = true
 	return p
 }
 
 func (p *Options) Completion(cmpl func()) *Options {
-	p.options = append(p.options, progressbar.OptionOnCompletion(cmpl))
+	p.completion = cmpl
 	return p
 }
 
 // EnableColorCodes enables  support for color codes ,you need there is a color code library
 // using mitchellh/colorstring
 func (p *Options) EnableColorCodes() *Options {
-	p.options = append(p.options, progressbar.OptionEnableColorCodes(true))
+	p.colorCodes = true
 	return p
 }
 
 func (p *Options) ElapsedTime(elapsedTime bool) *Options {
-	p.options = append(p.options, progressbar.OptionSetElapsedTime(elapsedTime))
+	p.elapsedTime = &elapsedTime
 	return p
 }
 
 // DisEnablePredictTime will also attempt to predict the time remaining.
 func (p *Options) DisEnablePredictTime() *Options {
-	p.options = append(p.options, progressbar.OptionSetPredictTime(false))
+	p.noPredictTime = true
 	return p
 }
 
 // EnableElapsedTimeOnFinish will keep the display of elapsed time on finish.
 func (p *Options) EnableElapsedTimeOnFinish() *Options {
-	p.options = append(p.options, progressbar.OptionShowElapsedTimeOnFinish())
+	p.elapsedTimeOnFinish = true
 	return p
 }
 
 func (p *Options) SetItsString(iterationString string) *Options {
-	p.options = append(p.options, progressbar.OptionSetItsString(iterationString))
+	p.itsString = &iterationString
 	return p
 }
 
 func (p *Options) ClearOnFinish() *Options {
-	p.options = append(p.options, progressbar.OptionClearOnFinish())
+	p.clearOnFinish = true
 	return p
 }
 
 // EnableShowBytes will update the progress bar
 // configuration settings to display/hide kBytes/Sec
 func (p *Options) EnableShowBytes() *Options {
-	p.options = append(p.options, progressbar.OptionShowBytes(true))
+	p.showBytes = true
 	return p
 }
 
@@ -148,24 +175,185 @@ func (p *Options) EnableShowBytes() *Options {
 //
 // Only useful in environments with support for ANSI escape sequences.
 func (p *Options) EnableANSICodes() *Options {
-	p.options = append(p.options, progressbar.OptionUseANSICodes(true))
+	p.ansiCodes = true
 	return p
 }
 
 // EnableIECUnits will enable IEC units (e.g. MiB) instead of the default
 // SI units (e.g. MB).
 func (p *Options) EnableIECUnits() *Options {
-	p.options = append(p.options, progressbar.OptionUseIECUnits(true))
+	p.iecUnits = true
 	return p
 }
 
 // EnableDescriptionAtLineEnd defines whether description should be written at line end instead of line start
 func (p *Options) EnableDescriptionAtLineEnd() *Options {
-	p.options = append(p.options, progressbar.OptionShowDescriptionAtLineEnd())
+	p.descriptionAtLineEnd = true
 	return p
 }
 
 func (p *Options) MaxDetailRow(row int) *Options {
-	p.options = append(p.options, progressbar.OptionSetMaxDetailRow(row))
+	p.maxDetailRow = &row
+	return p
+}
+
+// Clone returns a copy of the options that can be changed without
+// affecting p
+func (p *Options) Clone() *Options {
+	clone := *p
+	clone.spinnerCustom = slices.Clone(p.spinnerCustom)
+	return &clone
+}
+
+// Merge applies the settings made on other to p, the ones of other win.
+// Setting Width or FullWidth on other replaces both on p, the same goes
+// for SpinnerType and SpinnerCustom.
+func (p *Options) Merge(other *Options) *Options {
+	if other == nil {
+		return p
+	}
+	if other.writer != nil {
+		p.writer = other.writer
+	}
+	// 互斥的设置由 other 中设置的一方决定
+	if other.spinnerCustom != nil {
+		p.spinnerCustom, p.spinnerType = slices.Clone(other.spinnerCustom), nil
+	}
+	if other.spinnerType != nil {
+		p.spinnerType, p.spinnerCustom = other.spinnerType, nil
+	}
+	if other.width != nil {
+		p.width, p.fullWidth = other.width, other.fullWidth
+	}
+	if other.fullWidth {
+		p.fullWidth, p.width = true, other.width
+	}
+	if other.completion != nil {
+		p.completion = other.completion
+	}
+	p.showTotalBytes = pick(p.showTotalBytes, other.showTotalBytes)
+	p.spinnerChangeInterval = pick(p.spinnerChangeInterval, other.spinnerChangeInterval)
+	p.theme = pick(p.theme, other.theme)
+	p.renderBlankState = pick(p.renderBlankState, other.renderBlankState)
+	p.throttle = pick(p.throttle, other.throttle)
+	p.elapsedTime = pick(p.elapsedTime, other.elapsedTime)
+	p.itsString = pick(p.itsString, other.itsString)
+	p.maxDetailRow = pick(p.maxDetailRow, other.maxDetailRow)
+	p.invisible = p.invisible || other.invisible
+	p.showCount = p.showCount || other.showCount
+	p.showIts = p.showIts || other.showIts
+	p.colorCodes = p.colorCodes || other.colorCodes
+	p.noPredictTime = p.noPredictTime || other.noPredictTime
+	p.elapsedTimeOnFinish = p.elapsedTimeOnFinish || other.elapsedTimeOnFinish
+	p.clearOnFinish = p.clearOnFinish || other.clearOnFinish
+	p.showBytes = p.showBytes || other.showBytes
+	p.ansiCodes = p.ansiCodes || other.ansiCodes
+	p.iecUnits = p.iecUnits || other.iecUnits
+	p.descriptionAtLineEnd = p.descriptionAtLineEnd || other.descriptionAtLineEnd
 	return p
 }
+
+// pick returns later if it was set and base otherwise
+func pick[T any](base, later *T) *T {
+	if later != nil {
+		return later
+	}
+	return base
+}
+
+// GetWriter returns the writer the bar draws to, false if unset
+func (p *Options) GetWriter() (io.Writer, bool) {
+	return p.writer, p.writer != nil
+}
+
+// GetWidth returns the width of the bar, false if unset
+func (p *Options) GetWidth() (int, bool) {
+	return value(p.width)
+}
+
+// IsFullWidth reports whether the bar uses the full width of the terminal
+func (p *Options) IsFullWidth() bool {
+	return p.fullWidth
+}
+
+// GetTheme returns the theme of the bar, false if unset
+func (p *Options) GetTheme() (progressbar.Theme, bool) {
+	return value(p.theme)
+}
+
+// GetThrottle returns the minimum time between two renders, false if unset
+func (p *Options) GetThrottle() (time.Duration, bool) {
+	return value(p.throttle)
+}
+
+// IsVisible reports whether the bar is drawn at all
+func (p *Options) IsVisible() bool {
+	return !p.invisible
+}
+
+// GetSpinnerType returns the type of spinner, false if unset
+func (p *Options) GetSpinnerType() (int, bool) {
+	return value(p.spinnerType)
+}
+
+// GetSpinnerCustom returns the custom spinner, nil if unset
+func (p *Options) GetSpinnerCustom() []string {
+	return slices.Clone(p.spinnerCustom)
+}
+
+// GetSpinnerChangeInterval returns how often the spinner changes, false if unset
+func (p *Options) GetSpinnerChangeInterval() (time.Duration, bool) {
+	return value(p.spinnerChangeInterval)
+}
+
+// value dereferences v, false if it is nil
+func value[T any](v *T) (T, bool) {
+	if v == nil {
+		var zero T
+		return zero, false
+	}
+	return *v, true
+}
+
+// hasSpinner reports whether any spinner setting was made
+func (p *Options) hasSpinner() bool {
+	return p.spinnerType != nil || p.spinnerCustom != nil || p.spinnerChangeInterval != nil
+}
+
+// build translates the settings made to options of the underlying library
+func (p *Options) build() []progressbar.Option {
+	var opts []progressbar.Option
+	add := func(set bool, opt func() progressbar.Option) {
+		if set {
+			opts = append(opts, opt())
+		}
+	}
+	add(p.writer != nil, func() progressbar.Option { return progressbar.OptionSetWriter(p.writer) })
+	add(p.width != nil, func() progressbar.Option { return progressbar.OptionSetWidth(*p.width) })
+	add(p.fullWidth, progressbar.OptionFullWidth)
+	add(p.showTotalBytes != nil, func() progressbar.Option { return progressbar.OptionShowTotalBytes(*p.showTotalBytes) })
+	add(p.spinnerChangeInterval != nil, func() progressbar.Option {
+		return progressbar.OptionSetSpinnerChangeInterval(*p.spinnerChangeInterval)
+	})
+	add(p.spinnerType != nil, func() progressbar.Option { return progressbar.OptionSpinnerType(*p.spinnerType) })
+	add(p.spinnerCustom != nil, func() progressbar.Option { return progressbar.OptionSpinnerCustom(p.spinnerCustom) })
+	add(p.theme != nil, func() progressbar.Option { return progressbar.OptionSetTheme(*p.theme) })
+	add(p.invisible, func() progressbar.Option { return progressbar.OptionSetVisibility(false) })
+	add(p.renderBlankState != nil, func() progressbar.Option { return progressbar.OptionSetRenderBlankState(*p.renderBlankState) })
+	add(p.throttle != nil, func() progressbar.Option { return progressbar.OptionThrottle(*p.throttle) })
+	add(p.showCount, progressbar.OptionShowCount)
+	add(p.showIts, progressbar.OptionShowIts)
+	add(p.completion != nil, func() progressbar.Option { return progressbar.OptionOnCompletion(p.completion) })
+	add(p.colorCodes, func() progressbar.Option { return progressbar.OptionEnableColorCodes(true) })
+	add(p.elapsedTime != nil, func() progressbar.Option { return progressbar.OptionSetElapsedTime(*p.elapsedTime) })
+	add(p.noPredictTime, func() progressbar.Option { return progressbar.OptionSetPredictTime(false) })
+	add(p.elapsedTimeOnFinish, progressbar.OptionShowElapsedTimeOnFinish)
+	add(p.itsString != nil, func() progressbar.Option { return progressbar.OptionSetItsString(*p.itsString) })
+	add(p.clearOnFinish, progressbar.OptionClearOnFinish)
+	add(p.showBytes, func() progressbar.Option { return progressbar.OptionShowBytes(true) })
+	add(p.ansiCodes, func() progressbar.Option { return progressbar.OptionUseANSICodes(true) })
+	add(p.iecUnits, func() progressbar.Option { return progressbar.OptionUseIECUnits(true) })
+	add(p.descriptionAtLineEnd, progressbar.OptionShowDescriptionAtLineEnd)
+	add(p.maxDetailRow != nil, func() progressbar.Option { return progressbar.OptionSetMaxDetailRow(*p.maxDetailRow) })
+	return opts
+}
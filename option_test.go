@@ -0,0 +1,66 @@
+package progressbar
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	base := ProgressOptions().Width(20).Throttle(time.Second).SpinnerCustom("a", "b")
+	clone := base.Clone().Width(30)
+	clone.GetSpinnerCustom()[0] = "x"
+	if width, _ := base.GetWidth(); width != 20 {
+		t.Errorf("expected the clone not to change the base, got width %d", width)
+	}
+	if spinner := base.GetSpinnerCustom(); spinner[0] != "a" {
+		t.Errorf("expected the spinner of the base to be kept, got %v", spinner)
+	}
+
+	merged := base.Clone().Merge(ProgressOptions().Width(40).DisEnableVisibility())
+	if width, ok := merged.GetWidth(); !ok || width != 40 {
+		t.Errorf("expected the later width to win, got %d", width)
+	}
+	if throttle, ok := merged.GetThrottle(); !ok || throttle != time.Second {
+		t.Errorf("expected unset settings to keep the base, got %s", throttle)
+	}
+	if merged.IsVisible() || !base.IsVisible() {
+		t.Error("expected only the merged options to be invisible")
+	}
+	if _, ok := ProgressOptions().GetTheme(); ok {
+		t.Error("expected an unset theme")
+	}
+
+	var out strings.Builder
+	opts := ProgressOptions().Writer(&out)
+	bar := NewProgressBar().Total(2).Options(opts).Create()
+	opts.Writer(io.Discard)
+	_ = bar.Add(1)
+	if out.Len() == 0 {
+		t.Error("expected the bar to keep the writer it was created with")
+	}
+
+	full := quietOptions().FullWidth().Merge(ProgressOptions().Width(10))
+	if width, ok := full.GetWidth(); !ok || width != 10 || full.IsFullWidth() {
+		t.Errorf("expected the merged width to replace full width, got %d %v", width, full.IsFullWidth())
+	}
+	if _, err := New(1, full); err != nil {
+		t.Errorf("expected merged options to be valid, got %v", err)
+	}
+	if wide := quietOptions().Width(10).Merge(ProgressOptions().FullWidth()); !wide.IsFullWidth() {
+		t.Error("expected the merged full width to win")
+	} else if _, ok := wide.GetWidth(); ok {
+		t.Error("expected the merged full width to replace the width")
+	}
+
+	spinner := ProgressOptions().SpinnerCustom("a").Merge(ProgressOptions().SpinnerType(3))
+	if typ, ok := spinner.GetSpinnerType(); !ok || typ != 3 || spinner.GetSpinnerCustom() != nil {
+		t.Errorf("expected the merged spinner type to win, got %d %v", typ, spinner.GetSpinnerCustom())
+	}
+	if custom := ProgressOptions().SpinnerType(3).SpinnerCustom("a"); custom.GetSpinnerCustom() == nil {
+		t.Error("expected the later custom spinner to win")
+	} else if _, ok := custom.GetSpinnerType(); ok {
+		t.Error("expected the custom spinner to replace the type")
+	}
+}
@@ -2,9 +2,12 @@ package progressbar
 
 import (
 	"encoding/json"
-	"errors"
-	"log/slog"
-	"strings"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 )
@@ -13,12 +16,43 @@ const (
 	defaultMetricPort = "0.0.0.0:19999"
 )
 
+// ProgressBar is a terminal progress bar driven either by hand or by its
+// tasks. The builder methods configure it before use; every other method is
+// safe for concurrent use.
+//
+// A spinner, which is also what a bar without Total, tasks or children
+// shows, is drawn as soon as it is created and keeps a goroutine animating
+// it until it finishes.
+//
+// The other methods need the bar made by Create. Called before, they record
+// ErrNilBar, which Error reports, and return it or their zero value.
 type ProgressBar struct {
-	total int
-	bar   *progressbar.ProgressBar
-	opts  Options
-	tasks []ProgressTask
-	err   []error
+	total       int
+	totalSet    bool
+	concurrency int
+	policy      *FailurePolicy
+	retry       *RetryPolicy
+	timeout     time.Duration
+	hooks       hooks
+	limiter     *RateLimiter
+	checkpoint  CheckpointStore
+	saveEvery   *time.Duration // nil for defaultCheckpointInterval
+	signals     []os.Signal
+	parent      *ProgressBar
+	multi       *MultiBar
+	byTotal     bool
+	rolledUp    atomic.Bool
+	bar         atomic.Pointer[progressbar.ProgressBar]
+	opts        Options
+	tasks       []ProgressTask
+	barMu       sync.Mutex // serializes calls into bar, which does not lock in Clear and IsStarted
+	mu          sync.Mutex // guards err, currentTask, summary, stream and children
+	err         []*OpError
+	currentTask string
+	summary     Summary
+	stream      *run
+	children    []*ProgressBar
+	cancelled   atomic.Bool
 }
 
 func NewProgressBar() *ProgressBar {
@@ -28,35 +62,128 @@ func NewProgressBar() *ProgressBar {
 	}
 }
 
-// Error 返回所有累积的错误
+// New creates a bar with the given total, options and tasks in one step.
+// A total of 0 uses the sum of the task weights, or shows a spinner if
+// there are no tasks, and -1 shows a spinner. An invalid total, conflicting
+// options and invalid tasks are returned instead of a bar. The builder
+// ending in Create runs the same validation.
+func New(total int, opts *Options, tasks ...ProgressTask) (*ProgressBar, error) {
+	p := NewProgressBar().Tasks(tasks...)
+	if total != 0 {
+		p.total, p.totalSet = total, true
+	}
+	if opts != nil {
+		p.opts = *opts.Clone()
+	}
+	if err := p.create(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Error 返回所有累积的错误, 没有错误时返回 nil, 否则返回 *BarError
 func (p *ProgressBar) Error() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if len(p.err) == 0 {
 		return nil
 	}
-	return errors.New(strings.Join(p.errorMessages(), "; "))
+	return &BarError{Errors: slices.Clone(p.err)}
 }
 
-// errorMessages 获取所有错误消息
-func (p *ProgressBar) errorMessages() []string {
-	messages := make([]string, len(p.err))
-	for i, err := range p.err {
-		messages[i] = err.Error()
-	}
-	return messages
+// Errors returns the failures recorded so far, oldest first
+func (p *ProgressBar) Errors() []*OpError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.err)
+}
+
+// ClearErrors forgets the recorded failures once they were handled, so
+// that Add and the runs work again
+func (p *ProgressBar) ClearErrors() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = nil
+}
+
+// appendErr 并发安全地记录 op 导致的错误
+func (p *ProgressBar) appendErr(op string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = append(p.err, &OpError{Op: op, Time: time.Now(), Err: err})
 }
 
+// Create makes the underlying bar like New. An invalid configuration or
+// invalid tasks are recorded (see Error) instead and the bar is not made.
 func (p *ProgressBar) Create() *ProgressBar {
-	p.genericBar()
+	if err := p.create(); err != nil {
+		p.appendErr("Create", err)
+	}
 	return p
 }
 
+// create validates the configuration and the tasks and makes the underlying bar
+func (p *ProgressBar) create() error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	if _, err := p.checkTasks(); err != nil {
+		return err
+	}
+	p.genericBar()
+	return nil
+}
+
+// check validates the configuration the bar is created with
+func (p *ProgressBar) check() error {
+	// -1 表示 spinner
+	if p.totalSet && p.total < 1 && p.total != -1 {
+		return fmt.Errorf("%w (or -1 for a spinner), got %d", ErrInvalidTotal, p.total)
+	}
+	if p.opts.width != nil && p.opts.fullWidth {
+		return fmt.Errorf("%w: Width and FullWidth", ErrConflictingOptions)
+	}
+	// 按实际的总数判断, 包括由任务权重得出的总数
+	if max := p.maxSteps(); p.opts.hasSpinner() && max != -1 {
+		return fmt.Errorf("%w: spinner settings on a bar with total %d", ErrConflictingOptions, max)
+	}
+	return nil
+}
+
+// Total sets the maximum of the bar, -1 for a spinner. Without it the bar
+// uses the sum of the weights of its tasks and children, or shows a spinner
+// if it has none.
 func (p *ProgressBar) Total(total int) *ProgressBar {
 	p.total = total
+	p.totalSet = true
 	return p
 }
 
+// maxSteps returns the maximum the underlying bar is created with, -1 for a
+// spinner if neither Total nor any task or child gives one
+func (p *ProgressBar) maxSteps() int {
+	if p.totalSet {
+		return p.total
+	}
+	if steps := p.taskWeights() + p.childSteps(); steps > 0 {
+		return steps
+	}
+	return -1
+}
+
+// taskWeights returns the sum of the weights of all tasks
+func (p *ProgressBar) taskWeights() int {
+	sum := 0
+	for _, task := range p.tasks {
+		sum += task.weightOrDefault()
+	}
+	return sum
+}
+
+// Options sets the options the bar is created with. The bar keeps a copy,
+// later changes to opts do not affect it.
 func (p *ProgressBar) Options(opts *Options) *ProgressBar {
-	p.opts = *opts
+	p.opts = *opts.Clone()
 	return p
 }
 
@@ -65,22 +192,48 @@ func (p *ProgressBar) Tasks(tasks ...ProgressTask) *ProgressBar {
 	return p
 }
 
+// Retry sets the default retry policy for tasks that have none of their own
+func (p *ProgressBar) Retry(policy *RetryPolicy) *ProgressBar {
+	p.retry = policy
+	return p
+}
+
+// TaskTimeout sets the default time limit of every task attempt,
+// tasks exceeding it fail with ErrTaskTimeout
+func (p *ProgressBar) TaskTimeout(timeout time.Duration) *ProgressBar {
+	p.timeout = timeout
+	return p
+}
+
+// Concurrency sets how many workers AutoRun uses to execute tasks.
+// Values less than or equal to 1 run the tasks one after another.
+func (p *ProgressBar) Concurrency(n int) *ProgressBar {
+	p.concurrency = n
+	return p
+}
+
 // Prefix sets the prefix of the progress bar
 func (p *ProgressBar) Prefix(prefix string) {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Prefix", ErrNilBar)
 		return
 	}
-	p.bar.Describe(prefix)
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	bar.Describe(prefix)
 }
 
 // Suffix sets the suffix of the progress bar
 func (p *ProgressBar) Suffix(suffix string) {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Suffix", ErrNilBar)
 		return
 	}
-	p.bar.Describe(p.bar.State().Description + suffix)
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	bar.Describe(bar.State().Description + suffix)
 }
 
 // Metric starts an HTTP server dedicated to serving progress bar updates. This allows you to
@@ -89,54 +242,43 @@ func (p *ProgressBar) Suffix(suffix string) {
 //
 // hostPort specifies the address and port to bind the server to, for example, "0.0.0.0:19999".
 func (p *ProgressBar) Metric(hostPort string) {
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Metric", ErrNilBar)
+		return
+	}
 	if hostPort == "" {
 		hostPort = defaultMetricPort
 	}
-	p.bar.StartHTTPServer(hostPort)
+	bar.StartHTTPServer(hostPort)
 }
 
+// AddBar creates a child bar with the same options and maximum as p,
+// see Child
 func (p *ProgressBar) AddBar() *ProgressBar {
-	return &ProgressBar{
-		bar: progressbar.NewOptions(p.total, p.opts.options...),
-	}
+	return p.Child(p.maxSteps())
 }
 
 func (p *ProgressBar) genericBar() {
-	p.bar = progressbar.NewOptions(p.total, p.opts.options...)
+	p.bar.Store(newBar(p.maxSteps(), &p.opts))
 }
 
-type ProgressTask struct {
-	fn     any
-	params []any
-}
-
-func NewProgressTask(fn any, params ...any) ProgressTask {
-	return ProgressTask{
-		fn:     fn,
-		params: params,
+// newBar makes an underlying bar. A spinner is drawn once right away: the
+// goroutine the library starts to animate it reads the start time without
+// locking, so that time has to be set before the goroutine runs.
+func newBar(max int, opts *Options) *progressbar.ProgressBar {
+	options := opts.build()
+	if max == -1 {
+		options = append(options, progressbar.OptionSetRenderBlankState(true))
 	}
-}
-
-func (p *ProgressBar) AutoRun() error {
-	if p.Error() != nil {
-		return p.Error()
-	}
-	for _, task := range p.tasks {
-		if err := callFunc(task.fn, task.params...); err != nil {
-			slog.Error("AutoRun", "err", err)
-			return p.Exit()
-		}
-		if err := p.Next(); err != nil {
-			return err
-		}
-	}
-	return nil
+	return progressbar.NewOptions(max, options...)
 }
 
 // Next will increase the progress bar by 1
 // example:step + 1
 func (p *ProgressBar) Next() error {
-	if p.Error() != nil {
+	if p.bar.Load() == nil {
+		p.appendErr("Next", ErrNilBar)
 		return p.Error()
 	}
 	return p.Add(1)
@@ -144,86 +286,178 @@ func (p *ProgressBar) Next() error {
 
 // Add will add the specified amount to the progressbar
 func (p *ProgressBar) Add(num int) error {
-	if p.Error() != nil {
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Add", ErrNilBar)
 		return p.Error()
 	}
-	return p.bar.Add(num)
+	if err := p.Error(); err != nil {
+		return err
+	}
+	defer p.rollUp()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.Add(num)
+}
+
+// changeMax sets the maximum of the bar if it was created
+func (p *ProgressBar) changeMax(max int) {
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	if bar := p.bar.Load(); bar != nil && bar.GetMax() != max {
+		bar.ChangeMax(max)
+	}
 }
 
 // Finish will fill the bar to full
 func (p *ProgressBar) Finish() error {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Finish", ErrNilBar)
 		return p.Error()
 	}
-	return p.bar.Finish()
+	defer p.rollUp()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.Finish()
 }
 
 // Exit will exit the bar to keep current state
 func (p *ProgressBar) Exit() error {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Exit", ErrNilBar)
 		return p.Error()
 	}
-	return p.bar.Exit()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.Exit()
 }
 
 // Clear erases the progress bar from the current line
 func (p *ProgressBar) Clear() error {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Clear", ErrNilBar)
 		return p.Error()
 	}
-	return p.bar.Clear()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.Clear()
 }
 
 // Set will set the bar to a current number
 func (p *ProgressBar) Set(step int) error {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Set", ErrNilBar)
 		return p.Error()
 	}
-	return p.bar.Set(step)
+	defer p.rollUp()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.Set(step)
 }
 
 // IsFinished returns true if progress bar is completed
 func (p *ProgressBar) IsFinished() bool {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("IsFinished", ErrNilBar)
 		return false
 	}
-	return p.bar.IsFinished()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.IsFinished()
 }
 
 // IsStarted returns true if progress bar is started
 func (p *ProgressBar) IsStarted() bool {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("IsStarted", ErrNilBar)
 		return false
 	}
-	return p.bar.IsStarted()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.IsStarted()
 }
 
 // State returns the current state
 func (p *ProgressBar) State() progressbar.State {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("State", ErrNilBar)
 		return progressbar.State{}
 	}
-	return p.bar.State()
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	return bar.State()
 }
 
 // Describe will change the description shown before the progress, which
 // can be changed on the fly (as for a slow running process).
 func (p *ProgressBar) Describe(description string) {
-	if p.bar == nil {
-		p.err = append(p.err, ErrNilBar)
+	bar := p.bar.Load()
+	if bar == nil {
+		p.appendErr("Describe", ErrNilBar)
 		return
 	}
-	p.bar.Describe(description)
+	p.barMu.Lock()
+	defer p.barMu.Unlock()
+	bar.Describe(description)
+}
+
+// jsonState is the JSON representation of the bar returned by JSON
+type jsonState struct {
+	progressbar.State
+	// CurrentTask is the name of the task AutoRun started last
+	CurrentTask string `json:",omitempty"`
+	Summary
 }
 
+// JSON returns the state of the bar, the current task and the outcome counts
+// as JSON
 func (p *ProgressBar) JSON() string {
-	data, _ := json.Marshal(p.bar.State())
+	var state progressbar.State
+	if p.bar.Load() == nil {
+		p.appendErr("JSON", ErrNilBar)
+	} else {
+		state = p.State()
+	}
+	data, _ := json.Marshal(jsonState{
+		State:       state,
+		CurrentTask: p.CurrentTask(),
+		Summary:     p.Summary(),
+	})
 	return string(data)
 }
+
+// CurrentTask returns the name of the task AutoRun started last
+func (p *ProgressBar) CurrentTask() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currentTask
+}
+
+// Summary returns the outcome counts of the current or last run
+func (p *ProgressBar) Summary() Summary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.summary
+}
+
+// setSummary records the outcome counts of the running run
+func (p *ProgressBar) setSummary(summary Summary) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = summary
+}
+
+// setCurrentTask records name as the running task and shows it as description
+func (p *ProgressBar) setCurrentTask(name string) {
+	p.mu.Lock()
+	p.currentTask = name
+	p.mu.Unlock()
+	p.Describe(name)
+}
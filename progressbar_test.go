@@ -1,9 +1,12 @@
 package progressbar
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -527,3 +530,147 @@ func Task(num int) {
 	slog.Info("Task Done")
 	time.Sleep(time.Duration(num) * time.Second)
 }
+
+func TestConcurrentUse(t *testing.T) {
+	bar := NewProgressBar().Total(1000).Options(quietOptions()).Create()
+	uncreated := NewProgressBar()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = bar.Add(1)
+				bar.Describe(fmt.Sprintf("worker %d", i))
+				bar.Suffix(".")
+				bar.Prefix("step")
+				_ = bar.State()
+				_ = bar.JSON()
+				_ = bar.IsFinished()
+				_ = bar.IsStarted()
+				_ = bar.Clear()
+				_ = bar.Error()
+				uncreated.Suffix(".")
+				_ = uncreated.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := bar.State().CurrentNum; got != 800 {
+		t.Errorf("expected every Add to count, got %d", got)
+	}
+	if bar.Error() != nil {
+		t.Errorf("expected no errors, got %v", bar.Error())
+	}
+	if uncreated.Error() == nil {
+		t.Error("expected the uncreated bar to record its errors")
+	}
+}
+
+func TestBarErrors(t *testing.T) {
+	bar := NewProgressBar()
+	bar.Prefix("a")
+	if err := bar.Set(1); !errors.Is(err, ErrNilBar) {
+		t.Fatalf("expected ErrNilBar, got %v", err)
+	}
+	err := bar.Error()
+	var barErr *BarError
+	if !errors.As(err, &barErr) || len(barErr.Unwrap()) != 2 {
+		t.Fatalf("expected a BarError with two failures, got %v", err)
+	}
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Op != "Prefix" || opErr.Time.IsZero() {
+		t.Errorf("expected the first failure to come from Prefix, got %+v", opErr)
+	}
+	if ops := bar.Errors(); ops[1].Op != "Set" {
+		t.Errorf("expected the second failure to come from Set, got %q", ops[1].Op)
+	}
+	if !strings.Contains(err.Error(), "Prefix") || !strings.Contains(err.Error(), "; ") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	bar.ClearErrors()
+	if bar.Error() != nil || len(bar.Errors()) != 0 {
+		t.Error("expected ClearErrors to forget the failures")
+	}
+}
+
+func TestUncreatedBar(t *testing.T) {
+	bar := NewProgressBar()
+	if err := bar.Add(1); !errors.Is(err, ErrNilBar) {
+		t.Errorf("expected Add to report ErrNilBar, got %v", err)
+	}
+	if err := bar.Next(); !errors.Is(err, ErrNilBar) {
+		t.Errorf("expected Next to report ErrNilBar, got %v", err)
+	}
+	bar.Metric("")
+	if got := bar.JSON(); !json.Valid([]byte(got)) {
+		t.Errorf("expected valid JSON, got %q", got)
+	}
+	ops := make([]string, 0, 4)
+	for _, err := range bar.Errors() {
+		ops = append(ops, err.Op)
+	}
+	if strings.Join(ops, ",") != "Add,Next,Metric,JSON" {
+		t.Errorf("unexpected failed operations %v", ops)
+	}
+
+	for _, total := range []int{0, -2} {
+		invalid := NewProgressBar().Total(total).Options(quietOptions()).Create()
+		if err := invalid.Error(); !errors.Is(err, ErrInvalidTotal) {
+			t.Errorf("expected ErrInvalidTotal for total %d, got %v", total, err)
+		}
+	}
+	if spinner := NewProgressBar().Total(-1).Options(quietOptions()).Create(); spinner.Error() != nil {
+		t.Errorf("expected -1 to create a spinner, got %v", spinner.Error())
+	}
+	if empty := NewProgressBar().Options(quietOptions()).Create(); empty.State().Max != -1 {
+		t.Errorf("expected a bar with nothing to count to be a spinner, got max %d", empty.State().Max)
+	}
+
+	var called bool
+	run := NewProgressBar().Tasks(NewProgressTask(func() { called = true }))
+	if err := run.AutoRun(); !errors.Is(err, ErrNilBar) || called {
+		t.Errorf("expected AutoRun to report ErrNilBar before running tasks, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	bar, err := New(0, quietOptions(), NewFuncTask(func() error { return nil }).Weight(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bar.State().Max != 3 {
+		t.Errorf("expected the total to come from the task weights, got %d", bar.State().Max)
+	}
+	if err := bar.AutoRun(); err != nil || !bar.IsFinished() {
+		t.Errorf("expected the created bar to run, got %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		total int
+		opts  *Options
+		tasks []ProgressTask
+		want  error
+	}{
+		{"total", -3, quietOptions(), nil, ErrInvalidTotal},
+		{"width", 10, quietOptions().Width(20).FullWidth(), nil, ErrConflictingOptions},
+		{"spinner", 10, quietOptions().SpinnerType(3), nil, ErrConflictingOptions},
+		{"derived spinner", 0, quietOptions().SpinnerType(3), []ProgressTask{NewFuncTask(func() error { return nil })}, ErrConflictingOptions},
+		{"task", 1, quietOptions(), []ProgressTask{NewProgressTask(func(int) {}, "1")}, ErrInvalidTask},
+		{"dependency", 1, quietOptions(), []ProgressTask{NewFuncTask(func() error { return nil }).DependsOn("x")}, ErrMissingDependency},
+	}
+	for _, c := range cases {
+		bar, err := New(c.total, c.opts, c.tasks...)
+		if !errors.Is(err, c.want) || bar != nil {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+	if _, err := New(-1, quietOptions().SpinnerType(3)); err != nil {
+		t.Errorf("expected spinner settings on a spinner to be valid, got %v", err)
+	}
+	if bar, err := New(0, quietOptions().SpinnerType(3)); err != nil || bar.State().Max != -1 {
+		t.Errorf("expected a total of 0 without tasks to show a spinner like Create, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package progressbar
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket pacing task attempts. It is safe for
+// concurrent use and may be shared by several bars.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows perSecond task attempts per second on average and
+// up to burst attempts at once
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done and returns how long
+// it waited. A nil limiter never waits.
+func (l *RateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	return l.wait(ctx, nil)
+}
+
+// wait is Wait calling waiting with the delay before it blocks
+func (l *RateLimiter) wait(ctx context.Context, waiting func(time.Duration)) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return 0, nil
+	}
+	if waiting != nil {
+		waiting(delay)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	start := time.Now()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		l.cancel()
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long to wait until it is available
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a token reserved by a Wait that gave up
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
+
+// RateLimit paces the task attempts of AutoRun with limiter. The time spent
+// waiting for it is reported in TaskResult.Waited instead of Duration, and
+// the bar description shows when a task waits for it.
+func (p *ProgressBar) RateLimit(limiter *RateLimiter) *ProgressBar {
+	p.limiter = limiter
+	return p
+}
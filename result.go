@@ -0,0 +1,37 @@
+package progressbar
+
+import "time"
+
+// TaskResult is the outcome of a single task returned by RunCollect
+type TaskResult struct {
+	// Index is the position of the task in the order it was registered
+	Index int
+	// Name is the name of the task, empty for unnamed tasks
+	Name string
+	// Values holds the non-error return values of the task function
+	Values []any
+	// Err is the error of the last attempt, nil if the task succeeded
+	Err error
+	// Duration is the time spent on all attempts including backoff
+	Duration time.Duration
+	// Waited is the time spent waiting for the rate limiter, which is not
+	// part of Duration
+	Waited time.Duration
+	// Attempts is how often the task was called, 0 if it never ran
+	Attempts int
+	// Skipped is set if the task was skipped by its SkipIf predicate or
+	// returned ErrSkipTask
+	Skipped bool
+	// Resumed is set if the task finished in a previous run according to
+	// the checkpoint and was not run again
+	Resumed bool
+}
+
+// Summary counts the outcomes of the tasks of a run. Tasks skipped because
+// a dependency failed count as failed.
+type Summary struct {
+	Succeeded int `json:",omitempty"`
+	Failed    int `json:",omitempty"`
+	Skipped   int `json:",omitempty"`
+	Resumed   int `json:",omitempty"`
+}
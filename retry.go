@@ -0,0 +1,99 @@
+package progressbar
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns how long to wait before the given retry attempt, attempt
+// starts at 1 for the first retry
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff waits the same duration before every retry
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff doubles the wait before every retry starting at base,
+// never waiting longer than maxWait unless it is 0. jitter is a fraction
+// between 0 and 1 by which every wait is randomly shortened or lengthened.
+func ExponentialBackoff(base, maxWait time.Duration, jitter float64) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && (maxWait <= 0 || d < maxWait); i++ {
+			d *= 2
+		}
+		if maxWait > 0 && d > maxWait {
+			d = maxWait
+		}
+		if jitter > 0 {
+			d += time.Duration((rand.Float64()*2 - 1) * jitter * float64(d))
+		}
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// RetryPolicy describes how often and when a failed task is executed again
+type RetryPolicy struct {
+	maxAttempts int
+	backoff     Backoff
+	retryable   func(error) bool
+}
+
+// NewRetryPolicy returns a policy executing a task at most maxAttempts times
+// without waiting between attempts
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryPolicy{
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Backoff sets the wait between attempts
+func (r *RetryPolicy) Backoff(backoff Backoff) *RetryPolicy {
+	r.backoff = backoff
+	return r
+}
+
+// RetryIf only retries errors for which fn returns true
+func (r *RetryPolicy) RetryIf(fn func(error) bool) *RetryPolicy {
+	r.retryable = fn
+	return r
+}
+
+// attempts returns the maximum number of attempts, a nil policy runs once
+func (r *RetryPolicy) attempts() int {
+	if r == nil {
+		return 1
+	}
+	return r.maxAttempts
+}
+
+// shouldRetry reports whether err is worth another attempt, a nil policy
+// never retries
+func (r *RetryPolicy) shouldRetry(err error) bool {
+	return r != nil && (r.retryable == nil || r.retryable(err))
+}
+
+// wait blocks until the backoff before the given retry has elapsed or ctx is done
+func (r *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	if r.backoff == nil {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(r.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,544 @@
+package progressbar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailurePolicy decides whether AutoRun keeps scheduling tasks after a task
+// has failed.
+type FailurePolicy struct {
+	// maxFailures is the number of failures that stops the run, 0 means never
+	maxFailures int
+}
+
+var (
+	// FailFast stops the run at the first failed task. This is the default.
+	FailFast = FailurePolicy{maxFailures: 1}
+	// ContinueOnError runs every task and collects all failures.
+	ContinueOnError = FailurePolicy{maxFailures: 0}
+)
+
+// StopAfterFailures stops the run once n tasks have failed
+func StopAfterFailures(n int) FailurePolicy {
+	if n < 1 {
+		n = 1
+	}
+	return FailurePolicy{maxFailures: n}
+}
+
+// shouldStop reports whether the run stops after the given number of failures
+func (f FailurePolicy) shouldStop(failures int) bool {
+	return f.maxFailures > 0 && failures >= f.maxFailures
+}
+
+// OnFailure sets the failure policy used by AutoRun
+func (p *ProgressBar) OnFailure(policy FailurePolicy) *ProgressBar {
+	p.policy = &policy
+	return p
+}
+
+// failurePolicy returns the configured policy, defaulting to FailFast
+func (p *ProgressBar) failurePolicy() FailurePolicy {
+	if p.policy == nil {
+		return FailFast
+	}
+	return *p.policy
+}
+
+// AutoRun executes the registered tasks and advances the bar by the weight
+// of every finished task. When Concurrency is greater than 1 the tasks are
+// executed by that many workers, otherwise they run one after another in order.
+//
+// Tasks declaring dependencies with DependsOn start once all of them
+// succeeded, tasks whose dependency failed are skipped and reported with
+// ErrDependencyFailed. The dependency graph is validated before any task runs.
+//
+// Failed tasks are handled according to the FailurePolicy set by OnFailure.
+// The returned error joins a *TaskError for every failed task.
+func (p *ProgressBar) AutoRun() error {
+	return p.AutoRunContext(context.Background())
+}
+
+// AutoRunContext is like AutoRun but stops scheduling new tasks once ctx is
+// done. Tasks already running are waited for, the bar is marked as cancelled
+// and ctx.Err() is returned, joined with the errors of the tasks that failed
+// before. Task functions whose first parameter is a
+// context.Context receive ctx.
+func (p *ProgressBar) AutoRunContext(ctx context.Context) error {
+	_, err := p.RunCollect(ctx)
+	return err
+}
+
+// RunCollect is like AutoRunContext but also returns a TaskResult for every
+// registered task in registration order, including tasks that never ran.
+func (p *ProgressBar) RunCollect(ctx context.Context) ([]TaskResult, error) {
+	if p.Error() != nil {
+		return nil, p.Error()
+	}
+	if p.bar.Load() == nil {
+		p.appendErr("RunCollect", ErrNilBar)
+		return nil, p.Error()
+	}
+	graph, err := p.buildGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	r := p.newRun(ctx)
+	defer r.watchSignals()()
+	for _, task := range p.tasks {
+		r.add(task)
+	}
+	if !p.totalSet {
+		p.changeMax(p.maxSteps())
+	}
+	r.graph = graph
+	if r.checkpoint = p.checkpoint; r.checkpoint != nil {
+		weight, err := r.resume()
+		if err != nil {
+			return nil, err
+		}
+		if weight > 0 {
+			r.advanced = weight
+			if err := p.Add(weight); err != nil {
+				return nil, err
+			}
+		}
+	}
+	r.schedule(graph.roots(), nil, graph)
+	return r.results, r.finish()
+}
+
+// run holds the state of a single AutoRun
+type run struct {
+	p           *ProgressBar
+	ctx         context.Context
+	policy      FailurePolicy
+	description string
+	start       time.Time
+	checkpoint  CheckpointStore
+	graph       *taskGraph
+	completed   []string
+	saved       time.Time // when the checkpoint was last written
+	unsaved     bool      // whether completed changed since
+	resumed     map[int]bool
+	mu          sync.Mutex
+	tasks       []ProgressTask
+	results     []TaskResult
+	stopped     bool
+	summary     Summary
+	waited      time.Duration
+	failures    []*TaskError
+	blocked     []*TaskError
+	barErr      error
+	// undrained is set if the run stopped before its source was closed
+	undrained bool
+	// halt is closed once the run is interrupted by a signal
+	halt        chan struct{}
+	interrupted bool
+	// progressMu serializes advancing the bar with changing its total
+	progressMu    sync.Mutex
+	advanced      int
+	indeterminate bool
+}
+
+// newRun prepares a run of the bar without any tasks
+func (p *ProgressBar) newRun(ctx context.Context) *run {
+	p.cancelled.Store(false)
+	p.setSummary(Summary{})
+	r := &run{p: p, ctx: ctx, policy: p.failurePolicy(), start: time.Now(), halt: make(chan struct{})}
+	if p.bar.Load() != nil {
+		r.description = p.State().Description
+	}
+	return r
+}
+
+// add registers task with the run and returns its index
+func (r *run) add(task ProgressTask) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := len(r.tasks)
+	r.tasks = append(r.tasks, task)
+	r.results = append(r.results, TaskResult{Index: idx, Name: task.name})
+	return idx
+}
+
+// task returns the task at idx
+func (r *run) task(idx int) ProgressTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tasks[idx]
+}
+
+// schedule hands the ready tasks and the tasks received from source to the
+// workers until nothing is left to run. Tasks become ready through graph
+// once their dependencies finished.
+func (r *run) schedule(ready []int, source <-chan ProgressTask, graph *taskGraph) {
+	known := len(r.tasks)
+	if source != nil {
+		known = 0
+	}
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	results := make(chan TaskResult)
+	for i := 0; i < r.p.workers(known); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results <- r.execute(idx)
+			}
+		}()
+	}
+
+	// 调度依赖已全部完成的任务, 直到没有可调度或运行中的任务
+	inflight := 0
+	for {
+		// 断点中已完成的任务不再执行
+		for len(ready) > 0 && r.resumed[ready[0]] && !r.isStopped() {
+			idx := ready[0]
+			ready = ready[1:]
+			r.done(TaskResult{Index: idx, Name: r.task(idx).name, Resumed: true})
+			ready = append(ready, r.release(graph, idx, false)...)
+		}
+		var (
+			send chan<- int
+			next int
+			recv <-chan ProgressTask
+			stop <-chan struct{}
+			halt <-chan struct{}
+		)
+		if !r.isStopped() {
+			if len(ready) > 0 {
+				send, next = jobs, ready[0]
+			} else {
+				recv = source
+			}
+			stop, halt = r.ctx.Done(), r.halt
+		}
+		if send == nil && recv == nil && inflight == 0 {
+			break
+		}
+		select {
+		case send <- next:
+			ready = ready[1:]
+			inflight++
+		case task, ok := <-recv:
+			if !ok {
+				source = nil
+				continue
+			}
+			ready = append(ready, r.add(task))
+		case res := <-results:
+			inflight--
+			r.done(res)
+			if graph != nil {
+				ready = append(ready, r.release(graph, res.Index, res.Err != nil)...)
+			}
+		case <-stop:
+		case <-halt:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	r.undrained = source != nil
+}
+
+// execute calls the task at idx, retrying it according to its retry policy
+func (r *run) execute(idx int) TaskResult {
+	task := r.task(idx)
+	res := TaskResult{Index: idx, Name: task.name}
+	start := time.Now()
+	retry := task.retry
+	if retry == nil {
+		retry = r.p.retry
+	}
+	maxAttempts := retry.attempts()
+	description := r.description
+	if task.name != "" {
+		description = task.name
+		r.p.setCurrentTask(task.name)
+	}
+	event := TaskEvent{Index: idx, ID: task.key(), Name: task.name, Attempt: 1}
+	if skip, err := task.skipped(); skip || err != nil {
+		res.Skipped, res.Err = skip, err
+		res.Duration = time.Since(start)
+		event.Attempt, event.Elapsed, event.Err = 0, res.Duration, err
+		if skip {
+			r.p.hooks.call(r.p.hooks.taskSkip, event)
+		} else {
+			r.p.hooks.call(r.p.hooks.taskFailure, event)
+		}
+		return res
+	}
+	r.p.hooks.call(r.p.hooks.taskStart, event)
+
+	shown := description
+	for res.Attempts < maxAttempts {
+		if res.Attempts > 0 {
+			event.Attempt, event.Elapsed, event.Err = res.Attempts+1, time.Since(start), res.Err
+			r.p.hooks.call(r.p.hooks.retry, event)
+			if err := retry.wait(r.ctx, res.Attempts); err != nil {
+				break
+			}
+			shown = strings.TrimSpace(fmt.Sprintf("%s (attempt %d/%d)", description, res.Attempts+1, maxAttempts))
+			r.p.Describe(shown)
+		}
+		waited, err := r.p.limiter.wait(r.ctx, func(delay time.Duration) {
+			// 等待时间与执行时间分开展示
+			r.p.Describe(strings.TrimSpace(fmt.Sprintf("%s (rate limited, waiting %s)", shown, delay.Round(time.Millisecond))))
+		})
+		if waited > 0 {
+			r.p.Describe(shown)
+		}
+		res.Waited += waited
+		if err != nil {
+			if res.Err == nil {
+				res.Err = err
+			}
+			break
+		}
+		res.Attempts++
+		res.Values, res.Err = r.call(task)
+		if errors.Is(res.Err, ErrSkipTask) {
+			res.Skipped, res.Err = true, nil
+		}
+		if res.Err == nil || !retry.shouldRetry(res.Err) {
+			break
+		}
+	}
+	if maxAttempts > 1 {
+		r.p.Describe(description)
+	}
+	res.Duration = time.Since(start) - res.Waited
+
+	event.Attempt, event.Elapsed, event.Err = res.Attempts, res.Duration, res.Err
+	switch {
+	case res.Skipped:
+		r.p.hooks.call(r.p.hooks.taskSkip, event)
+	case res.Err != nil:
+		r.p.hooks.call(r.p.hooks.taskFailure, event)
+	default:
+		r.p.hooks.call(r.p.hooks.taskSuccess, event)
+	}
+	return res
+}
+
+// call runs a single attempt of task, giving up once its timeout elapsed.
+// A task that ignores its context keeps running in the background after
+// the timeout.
+func (r *run) call(task ProgressTask) ([]any, error) {
+	timeout := task.timeout
+	if timeout <= 0 {
+		timeout = r.p.timeout
+	}
+	if timeout <= 0 {
+		return task.invoke(r.ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
+	defer cancel()
+	type outcome struct {
+		values []any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		values, err := task.invoke(ctx)
+		done <- outcome{values: values, err: err}
+	}()
+
+	var err error
+	select {
+	case out := <-done:
+		if out.err == nil {
+			return out.values, nil
+		}
+		err = out.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && r.ctx.Err() == nil {
+		return nil, fmt.Errorf("%w after %s", ErrTaskTimeout, timeout)
+	}
+	return nil, err
+}
+
+// isStopped reports whether no more tasks should be scheduled
+func (r *run) isStopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopped || r.ctx.Err() != nil
+}
+
+// done records the outcome of a task and advances the bar by its weight
+func (r *run) done(res TaskResult) {
+	idx, err := res.Index, res.Err
+	r.mu.Lock()
+	r.results[idx] = res
+	r.waited += res.Waited
+	switch {
+	case res.Resumed:
+		r.summary.Resumed++
+		r.p.setSummary(r.summary)
+		r.mu.Unlock()
+		return
+	case res.Skipped:
+		r.summary.Skipped++
+	case err != nil:
+		r.summary.Failed++
+		r.failures = append(r.failures, &TaskError{Index: idx, Name: res.Name, Err: err})
+		if r.policy.shouldStop(len(r.failures)) {
+			r.stopped = true
+			r.p.setSummary(r.summary)
+			r.mu.Unlock()
+			return
+		}
+	default:
+		r.summary.Succeeded++
+	}
+	r.p.setSummary(r.summary)
+	r.mu.Unlock()
+	r.advance(idx)
+	// 失败的任务不记入检查点, 重新运行时需要再次执行
+	if r.checkpoint != nil && err == nil {
+		r.save(idx)
+	}
+}
+
+// advance moves the bar forward by the weight of the task at idx
+func (r *run) advance(idx int) {
+	weight := r.task(idx).weightOrDefault()
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	r.advanced += weight
+	if err := r.p.Add(weight); err != nil {
+		r.abort(err)
+	}
+}
+
+// abort stops the run because of an error that is not caused by a task
+func (r *run) abort(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	if r.barErr == nil {
+		r.barErr = err
+	}
+}
+
+// release marks the task at idx as finished in graph and returns the tasks
+// that can be scheduled now. Dependents of failed tasks are skipped.
+func (r *run) release(graph *taskGraph, idx int, failed bool) []int {
+	if r.isStopped() {
+		return nil
+	}
+	var ready []int
+	for _, dep := range graph.release(idx, failed) {
+		if graph.blocked[dep] == "" {
+			ready = append(ready, dep)
+			continue
+		}
+		r.block(dep, graph.blocked[dep])
+		ready = append(ready, r.release(graph, dep, true)...)
+	}
+	return ready
+}
+
+// block records that the task at idx did not run because its dependency
+// failed, still advancing the bar by its weight
+func (r *run) block(idx int, dependency string) {
+	err := fmt.Errorf("%w: %s", ErrDependencyFailed, dependency)
+	r.mu.Lock()
+	r.results[idx].Err = err
+	task := r.tasks[idx]
+	r.blocked = append(r.blocked, &TaskError{Index: idx, Name: task.name, Err: err})
+	r.summary.Failed++
+	r.p.setSummary(r.summary)
+	r.mu.Unlock()
+	r.p.hooks.call(r.p.hooks.taskFailure, TaskEvent{Index: idx, ID: task.key(), Name: task.name, Err: err})
+	r.advance(idx)
+}
+
+// finish leaves the bar in its final state, builds the result of the run and
+// reports it to the OnRunComplete hook
+func (r *run) finish() error {
+	err := r.result()
+	if r.p.hooks.runComplete != nil {
+		r.p.hooks.runComplete(RunEvent{
+			Tasks:   len(r.tasks),
+			Summary: r.summary,
+			Elapsed: time.Since(r.start),
+			Waited:  r.waited,
+			Err:     err,
+		})
+	}
+	return err
+}
+
+// result builds the error returned by the run
+func (r *run) result() error {
+	// 运行结束时写入节流期间未保存的任务
+	r.flush()
+	r.mu.Lock()
+	interrupted, stopped := r.interrupted, r.stopped
+	r.mu.Unlock()
+	processed := r.summary.Succeeded + r.summary.Failed + r.summary.Skipped + r.summary.Resumed
+	if interrupted {
+		return errors.Join(append(r.taskErrors(), ErrInterrupted, r.barErr, r.p.Exit())...)
+	}
+	if err := r.ctx.Err(); err != nil && (processed < len(r.tasks) || r.undrained) {
+		r.p.cancelled.Store(true)
+		return errors.Join(append(r.taskErrors(), err)...)
+	}
+	if len(r.failures) == 0 {
+		if r.checkpoint != nil && r.barErr == nil && processed == len(r.tasks) {
+			return r.checkpoint.Clear()
+		}
+		return r.barErr
+	}
+
+	errs := append(r.taskErrors(), r.barErr)
+	if stopped {
+		errs = append(errs, r.p.Exit())
+	}
+	return errors.Join(errs...)
+}
+
+// taskErrors returns the errors of the failed and blocked tasks by index
+func (r *run) taskErrors() []error {
+	taskErrs := append(r.failures, r.blocked...)
+	sort.Slice(taskErrs, func(i, j int) bool {
+		return taskErrs[i].Index < taskErrs[j].Index
+	})
+	errs := make([]error, 0, len(taskErrs)+3)
+	for _, taskErr := range taskErrs {
+		errs = append(errs, taskErr)
+	}
+	return errs
+}
+
+// workers returns the number of goroutines used to run the given number of
+// tasks, 0 meaning the number is not known yet
+func (p *ProgressBar) workers(tasks int) int {
+	if p.concurrency <= 1 {
+		return 1
+	}
+	if tasks > 0 && p.concurrency > tasks {
+		return tasks
+	}
+	return p.concurrency
+}
+
+// IsCancelled returns true if the last AutoRunContext was stopped by its
+// context. Unlike Exit, a cancelled bar keeps its state and does not trigger
+// the completion callback.
+func (p *ProgressBar) IsCancelled() bool {
+	return p.cancelled.Load()
+}
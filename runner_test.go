@@ -0,0 +1,1117 @@
+package progressbar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func quietOptions() *Options {
+	return ProgressOptions().Writer(io.Discard)
+}
+
+// syncBuffer is a strings.Builder safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAutoRunConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	task := func() error {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	}
+	tasks := make([]ProgressTask, 0, 40)
+	for i := 0; i < 40; i++ {
+		tasks = append(tasks, NewProgressTask(task))
+	}
+	bar := NewProgressBar().
+		Total(len(tasks)).
+		Options(quietOptions()).
+		Tasks(tasks...).
+		Concurrency(8).
+		Create()
+	if err := bar.AutoRun(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bar.State().CurrentNum; got != 40 {
+		t.Errorf("expected 40 steps, got %d", got)
+	}
+	if peak.Load() < 2 || peak.Load() > 8 {
+		t.Errorf("expected between 2 and 8 concurrent tasks, got %d", peak.Load())
+	}
+}
+
+func TestAutoRunSequentialOrder(t *testing.T) {
+	var order []int
+	tasks := make([]ProgressTask, 0, 5)
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, NewProgressTask(func(n int) { order = append(order, n) }, i))
+	}
+	bar := NewProgressBar().Total(5).Options(quietOptions()).Tasks(tasks...).Create()
+	if err := bar.AutoRun(); err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range order {
+		if i != n {
+			t.Fatalf("tasks ran out of order: %v", order)
+		}
+	}
+}
+
+func TestAutoRunContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ran atomic.Int32
+	tasks := make([]ProgressTask, 0, 10)
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, NewProgressTask(func(ctx context.Context, n int) error {
+			if ran.Add(1) == 3 {
+				cancel()
+			}
+			return nil
+		}, i))
+	}
+	bar := NewProgressBar().Total(10).Options(quietOptions()).Tasks(tasks...).Create()
+	err := bar.AutoRunContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !bar.IsCancelled() {
+		t.Error("expected bar to be cancelled")
+	}
+	if ran.Load() != 3 {
+		t.Errorf("expected 3 tasks to run, got %d", ran.Load())
+	}
+}
+
+func TestAutoRunContextCancelAfterFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errFail := errors.New("fail")
+	bar := NewProgressBar().
+		Total(3).
+		Options(quietOptions()).
+		OnFailure(ContinueOnError).
+		Tasks(
+			NewProgressTask(func() error { return errFail }),
+			NewProgressTask(func() { cancel() }),
+			NewProgressTask(func() {}),
+		).
+		Create()
+	err := bar.AutoRunContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !errors.Is(err, errFail) {
+		t.Errorf("expected the failed task in %v", err)
+	}
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 0 {
+		t.Errorf("expected a TaskError for task 0, got %v", err)
+	}
+}
+
+func TestAutoRunContextInjection(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	var got any
+	bar := NewProgressBar().
+		Total(1).
+		Options(quietOptions()).
+		Tasks(NewProgressTask(func(ctx context.Context) { got = ctx.Value(key{}) })).
+		Create()
+	if err := bar.AutoRunContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got != "value" {
+		t.Errorf("expected task to receive the run context, got %v", got)
+	}
+	if bar.IsCancelled() {
+		t.Error("expected bar not to be cancelled")
+	}
+}
+
+func TestAutoRunFailurePolicy(t *testing.T) {
+	errFail := errors.New("fail")
+	newTasks := func(calls *atomic.Int32) []ProgressTask {
+		tasks := make([]ProgressTask, 0, 6)
+		for i := 0; i < 6; i++ {
+			tasks = append(tasks, NewProgressTask(func(n int) error {
+				calls.Add(1)
+				if n%2 == 1 {
+					return errFail
+				}
+				return nil
+			}, i))
+		}
+		return tasks
+	}
+	tests := []struct {
+		name      string
+		policy    *FailurePolicy
+		wantCalls int32
+		wantFails int
+	}{
+		{name: "default", wantCalls: 2, wantFails: 1},
+		{name: "fail fast", policy: &FailFast, wantCalls: 2, wantFails: 1},
+		{name: "continue", policy: &ContinueOnError, wantCalls: 6, wantFails: 3},
+		{name: "stop after 2", policy: func() *FailurePolicy { f := StopAfterFailures(2); return &f }(), wantCalls: 4, wantFails: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			bar := NewProgressBar().Total(6).Options(quietOptions()).Tasks(newTasks(&calls)...)
+			if tt.policy != nil {
+				bar.OnFailure(*tt.policy)
+			}
+			err := bar.Create().AutoRun()
+			if !errors.Is(err, errFail) {
+				t.Fatalf("expected error wrapping errFail, got %v", err)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls.Load())
+			}
+			var fails int
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var taskErr *TaskError
+				if errors.As(e, &taskErr) {
+					if taskErr.Index%2 != 1 {
+						t.Errorf("unexpected failed task index %d", taskErr.Index)
+					}
+					fails++
+				}
+			}
+			if fails != tt.wantFails {
+				t.Errorf("expected %d task errors, got %d", tt.wantFails, fails)
+			}
+		})
+	}
+}
+
+func TestAutoRunRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+	var flaky, fatal atomic.Int32
+	var descriptions []string
+	bar := NewProgressBar().
+		Total(3).
+		Options(quietOptions()).
+		Retry(NewRetryPolicy(3).
+			Backoff(ExponentialBackoff(time.Millisecond, 4*time.Millisecond, 0.5)).
+			RetryIf(func(err error) bool { return errors.Is(err, errTransient) })).
+		OnFailure(ContinueOnError).
+		Create()
+	bar.Tasks(
+		NewProgressTask(func() error {
+			if flaky.Add(1) < 3 {
+				return errTransient
+			}
+			descriptions = append(descriptions, bar.State().Description)
+			return nil
+		}),
+		NewProgressTask(func() error {
+			fatal.Add(1)
+			return errFatal
+		}),
+		NewProgressTask(func() error { return errTransient }).
+			Retry(NewRetryPolicy(2).Backoff(ConstantBackoff(time.Millisecond))),
+	)
+	err := bar.AutoRun()
+	if flaky.Load() != 3 {
+		t.Errorf("expected flaky task to run 3 times, got %d", flaky.Load())
+	}
+	if fatal.Load() != 1 {
+		t.Errorf("expected non-retryable task to run once, got %d", fatal.Load())
+	}
+	if len(descriptions) != 1 || descriptions[0] != "(attempt 3/3)" {
+		t.Errorf("unexpected description during retry: %v", descriptions)
+	}
+	if !errors.Is(err, errFatal) || !errors.Is(err, errTransient) {
+		t.Errorf("expected both failures to be reported, got %v", err)
+	}
+	if got := bar.State().CurrentNum; got != 3 {
+		t.Errorf("expected 3 steps, got %d", got)
+	}
+}
+
+func TestAutoRunTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	bar := NewProgressBar().
+		Total(3).
+		Options(quietOptions()).
+		TaskTimeout(20*time.Millisecond).
+		OnFailure(ContinueOnError).
+		Tasks(
+			NewProgressTask(func(ctx context.Context) error {
+				<-ctx.Done()
+				close(cancelled)
+				return ctx.Err()
+			}),
+			NewProgressTask(func() { time.Sleep(50 * time.Millisecond) }).Timeout(200*time.Millisecond),
+			NewProgressTask(func() { time.Sleep(time.Second) }),
+		).
+		Create()
+	start := time.Now()
+	err := bar.AutoRun()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected timeouts to stop waiting, took %s", elapsed)
+	}
+	if !errors.Is(err, ErrTaskTimeout) {
+		t.Fatalf("expected ErrTaskTimeout, got %v", err)
+	}
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 0 {
+		t.Errorf("expected first task to time out, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("expected 2 timed out tasks, got %d: %v", n, err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the task context to be cancelled")
+	}
+}
+
+func TestAutoRunNamedTasks(t *testing.T) {
+	errFail := errors.New("fail")
+	var seen []string
+	bar := NewProgressBar().Total(3).Options(quietOptions()).OnFailure(ContinueOnError).Create()
+	record := func() { seen = append(seen, bar.State().Description) }
+	bar.Tasks(
+		NewNamedProgressTask("download", record),
+		NewProgressTask(record).Named("extract"),
+		NewNamedProgressTask("install", func() error { return errFail }),
+	)
+	err := bar.AutoRun()
+	if len(seen) != 2 || seen[0] != "download" || seen[1] != "extract" {
+		t.Errorf("unexpected descriptions: %v", seen)
+	}
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "install" {
+		t.Fatalf("expected error of task install, got %v", err)
+	}
+	if want := "go-progressbar:task 2 (install): fail"; taskErr.Error() != want {
+		t.Errorf("expected %q, got %q", want, taskErr.Error())
+	}
+	var state struct {
+		CurrentNum  int64
+		CurrentTask string
+	}
+	if err := json.Unmarshal([]byte(bar.JSON()), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.CurrentTask != "install" || state.CurrentNum != 3 {
+		t.Errorf("unexpected JSON state: %s", bar.JSON())
+	}
+}
+
+func TestAutoRunWeightedTasks(t *testing.T) {
+	var steps []int64
+	bar := NewProgressBar().Options(quietOptions())
+	record := func() { steps = append(steps, bar.State().CurrentNum) }
+	bar.Tasks(
+		NewProgressTask(record),
+		NewProgressTask(record).Weight(5),
+		NewProgressTask(record).Weight(4),
+	).Create()
+	if got := bar.State().Max; got != 10 {
+		t.Fatalf("expected total derived from weights to be 10, got %d", got)
+	}
+	if err := bar.AutoRun(); err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[0] != 0 || steps[1] != 1 || steps[2] != 6 {
+		t.Errorf("unexpected progress before each task: %v", steps)
+	}
+	if !bar.IsFinished() {
+		t.Error("expected bar to be finished")
+	}
+
+	explicit := NewProgressBar().Total(20).Options(quietOptions()).
+		Tasks(NewProgressTask(func() {}).Weight(5)).Create()
+	if err := explicit.AutoRun(); err != nil {
+		t.Fatal(err)
+	}
+	if s := explicit.State(); s.Max != 20 || s.CurrentNum != 5 {
+		t.Errorf("expected 5/20, got %d/%d", s.CurrentNum, s.Max)
+	}
+}
+
+func TestAutoRunDependencies(t *testing.T) {
+	errFail := errors.New("fail")
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	step := func(name string) func() {
+		return func() {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}
+	}
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		Concurrency(4).
+		OnFailure(ContinueOnError).
+		Tasks(
+			NewNamedProgressTask("link", step("link")).DependsOn("compile-a", "compile-b"),
+			NewNamedProgressTask("compile-a", step("compile-a")).DependsOn("fetch"),
+			NewNamedProgressTask("compile-b", step("compile-b")).DependsOn("fetch"),
+			NewNamedProgressTask("fetch", step("fetch")),
+			NewProgressTask(func() error { return errFail }).ID("lint"),
+			NewNamedProgressTask("release", step("release")).DependsOn("link", "lint").Weight(3),
+			NewNamedProgressTask("notify", step("notify")).DependsOn("release"),
+		).
+		Create()
+	err := bar.AutoRun()
+
+	pos := make(map[string]int, len(order))
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != 4 || pos["fetch"] != 0 || pos["link"] != 3 {
+		t.Errorf("tasks ran in an invalid order: %v", order)
+	}
+	if !errors.Is(err, errFail) || !errors.Is(err, ErrDependencyFailed) {
+		t.Fatalf("expected failure and skipped dependents, got %v", err)
+	}
+	var skipped []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var taskErr *TaskError
+		if errors.As(e, &taskErr) && errors.Is(taskErr, ErrDependencyFailed) {
+			skipped = append(skipped, taskErr.Name)
+		}
+	}
+	if len(skipped) != 2 || skipped[0] != "release" || skipped[1] != "notify" {
+		t.Errorf("unexpected skipped tasks: %v", skipped)
+	}
+	if s := bar.State(); s.CurrentNum != s.Max || s.Max != 9 {
+		t.Errorf("expected skipped tasks to fill the bar, got %d/%d", s.CurrentNum, s.Max)
+	}
+}
+
+func TestAutoRunInvalidDependencies(t *testing.T) {
+	noop := func() {}
+	tests := []struct {
+		name  string
+		tasks []ProgressTask
+		want  error
+	}{
+		{
+			name:  "missing",
+			tasks: []ProgressTask{NewProgressTask(noop).DependsOn("nope")},
+			want:  ErrMissingDependency,
+		},
+		{
+			name:  "duplicate",
+			tasks: []ProgressTask{NewProgressTask(noop).ID("a"), NewProgressTask(noop).ID("a")},
+			want:  ErrDuplicateTaskID,
+		},
+		{
+			name: "ambiguous",
+			tasks: []ProgressTask{
+				NewNamedProgressTask("upload", noop),
+				NewNamedProgressTask("upload", noop),
+				NewProgressTask(noop).DependsOn("upload"),
+			},
+			want: ErrDuplicateTaskID,
+		},
+		{
+			name: "cycle",
+			tasks: []ProgressTask{
+				NewNamedProgressTask("a", noop).DependsOn("c"),
+				NewNamedProgressTask("b", noop).DependsOn("a"),
+				NewNamedProgressTask("c", noop).DependsOn("b"),
+			},
+			want: ErrDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran atomic.Bool
+			tasks := append(tt.tasks, NewProgressTask(func() { ran.Store(true) }))
+			bar := NewProgressBar().Options(quietOptions()).Tasks(tasks...).Create()
+			if err := bar.AutoRun(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+			if ran.Load() {
+				t.Error("expected no task to run")
+			}
+		})
+	}
+}
+
+func TestAutoRunDuplicateNames(t *testing.T) {
+	var ran atomic.Int32
+	upload := func() { ran.Add(1) }
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		Tasks(
+			NewNamedProgressTask("upload", upload),
+			NewNamedProgressTask("upload", upload),
+			NewNamedProgressTask("upload", upload).ID("last"),
+			NewNamedProgressTask("notify", upload).DependsOn("last"),
+		).
+		Create()
+	if err := bar.AutoRun(); err != nil {
+		t.Fatalf("expected tasks sharing a name to run, got %v", err)
+	}
+	if ran.Load() != 4 {
+		t.Errorf("expected 4 tasks to run, ran %d", ran.Load())
+	}
+}
+
+func TestGenericTasks(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, 2)
+	var sum atomic.Int64
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		Tasks(
+			NewFuncTask(func() error { sum.Add(1); return nil }),
+			NewFuncTask1(func(n int64) error { sum.Add(n); return nil }, 10),
+			NewContextTask(func(ctx context.Context) error { sum.Add(int64(ctx.Value(key{}).(int))); return nil }),
+			NewContextTask1(func(ctx context.Context, s string) (int, error) {
+				sum.Add(int64(len(s)))
+				return len(s), nil
+			}, "abc"),
+		).
+		Create()
+	if err := bar.AutoRunContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Load() != 16 {
+		t.Errorf("expected sum 16, got %d", sum.Load())
+	}
+}
+
+func TestInvalidReflectiveTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     any
+		params []any
+	}{
+		{name: "not a function", fn: 42},
+		{name: "wrong count", fn: func(int) {}, params: []any{1, 2}},
+		{name: "wrong type", fn: func(int) {}, params: []any{"1"}},
+		{name: "nil value", fn: func(int) {}, params: []any{nil}},
+		{name: "variadic count", fn: func(string, ...int) {}},
+		{name: "variadic type", fn: func(string, ...int) {}, params: []any{"a", 1, "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := callFunc(context.Background(), tt.fn, tt.params...)
+			if !errors.Is(err, ErrInvalidTask) {
+				t.Errorf("expected ErrInvalidTask, got %v", err)
+			}
+		})
+	}
+	if _, err := callFunc(context.Background(), func(err error, s []int) {}, nil, nil); err != nil {
+		t.Errorf("expected nil to be accepted for nillable parameters, got %v", err)
+	}
+}
+
+// myErr is a concrete error type returned by reflective tasks
+type myErr struct{ msg string }
+
+func (e *myErr) Error() string { return e.msg }
+
+func TestReflectiveTaskCalls(t *testing.T) {
+	sum := func(ns ...int) int {
+		total := 0
+		for _, n := range ns {
+			total += n
+		}
+		return total
+	}
+	tests := []struct {
+		name   string
+		fn     any
+		params []any
+		want   int
+	}{
+		{name: "variadic values", fn: sum, params: []any{1, 2, 3}, want: 6},
+		{name: "variadic slice", fn: sum, params: []any{[]int{4, 5}}, want: 9},
+		{name: "variadic empty", fn: sum, want: 0},
+		{name: "variadic context", fn: func(ctx context.Context, ns ...int) int { return sum(ns...) }, params: []any{7, 8}, want: 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := callFunc(context.Background(), tt.fn, tt.params...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(values) != 1 || values[0] != tt.want {
+				t.Errorf("expected [%d], got %v", tt.want, values)
+			}
+		})
+	}
+
+	failing := &myErr{msg: "failed"}
+	values, err := callFunc(context.Background(), func() (int, *myErr) { return 1, failing })
+	if err != failing || len(values) != 1 {
+		t.Errorf("expected a concrete error type to fail the call, got %v %v", values, err)
+	}
+	if _, err := callFunc(context.Background(), func() *myErr { return nil }); err != nil {
+		t.Errorf("expected a nil concrete error to succeed, got %v", err)
+	}
+}
+
+func TestAutoRunPanic(t *testing.T) {
+	errBoom := errors.New("boom")
+	var ran atomic.Bool
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		OnFailure(ContinueOnError).
+		Tasks(
+			NewProgressTask(func() { panic("kaboom") }),
+			NewFuncTask(func() error { panic(errBoom) }).Timeout(time.Second),
+			NewProgressTask(func() { ran.Store(true) }),
+		).
+		Create()
+	err := bar.AutoRun()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "kaboom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected a panic error with stack, got %v", err)
+	}
+	if !errors.Is(err, errBoom) {
+		t.Errorf("expected panic value to be unwrapped, got %v", err)
+	}
+	if !ran.Load() {
+		t.Error("expected tasks after the panic to run")
+	}
+}
+
+func TestRunCollect(t *testing.T) {
+	errFail := errors.New("fail")
+	var calls atomic.Int32
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		OnFailure(ContinueOnError).
+		Tasks(
+			NewNamedProgressTask("sum", func(a, b int) (int, error) { return a + b, nil }, 1, 2),
+			NewContextTask1(func(_ context.Context, s string) (string, error) { return s + s, nil }, "ab"),
+			NewProgressTask(func() error {
+				if calls.Add(1) < 2 {
+					return errFail
+				}
+				return nil
+			}).Retry(NewRetryPolicy(3)),
+			NewProgressTask(func() (string, error) { return "", errFail }).ID("broken"),
+			NewProgressTask(func() {}).DependsOn("broken"),
+		).
+		Create()
+	results, err := bar.RunCollect(context.Background())
+	if !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	for i, res := range results {
+		if res.Index != i {
+			t.Errorf("result %d has index %d", i, res.Index)
+		}
+	}
+	if res := results[0]; res.Name != "sum" || len(res.Values) != 1 || res.Values[0] != 3 || res.Attempts != 1 {
+		t.Errorf("unexpected result of sum: %+v", res)
+	}
+	if res := results[1]; len(res.Values) != 1 || res.Values[0] != "abab" {
+		t.Errorf("unexpected result of generic task: %+v", res)
+	}
+	if res := results[2]; res.Err != nil || res.Attempts != 2 || res.Duration <= 0 {
+		t.Errorf("unexpected result of retried task: %+v", res)
+	}
+	if res := results[3]; !errors.Is(res.Err, errFail) || res.Attempts != 1 {
+		t.Errorf("unexpected result of failed task: %+v", res)
+	}
+	if res := results[4]; !errors.Is(res.Err, ErrDependencyFailed) || res.Attempts != 0 {
+		t.Errorf("unexpected result of skipped task: %+v", res)
+	}
+}
+
+func TestRunStream(t *testing.T) {
+	bar := NewProgressBar().Options(quietOptions()).Concurrency(2).Create()
+	var maxBefore, maxAfter int64
+	tasks := make(chan ProgressTask)
+	go func() {
+		defer close(tasks)
+		for i := 0; i < 3; i++ {
+			tasks <- NewProgressTask(func() {})
+		}
+		recorded := make(chan struct{})
+		tasks <- NewProgressTask(func() { maxBefore = bar.State().Max; close(recorded) })
+		<-recorded
+		if err := bar.ReportTotal(8); err != nil {
+			t.Error(err)
+		}
+		for i := 0; i < 3; i++ {
+			tasks <- NewProgressTask(func() {})
+		}
+		tasks <- NewProgressTask(func() { maxAfter = bar.State().Max })
+	}()
+	if err := bar.RunStream(context.Background(), tasks); err != nil {
+		t.Fatal(err)
+	}
+	if maxBefore != -1 || maxAfter != 8 {
+		t.Errorf("expected spinner before and total after the report, got %d and %d", maxBefore, maxAfter)
+	}
+	if s := bar.State(); s.CurrentNum != 8 || s.Max != 8 {
+		t.Errorf("expected 8/8, got %d/%d", s.CurrentNum, s.Max)
+	}
+}
+
+func TestRunSeq(t *testing.T) {
+	errFail := errors.New("fail")
+	var produced atomic.Int32
+	seq := func(yield func(ProgressTask) bool) {
+		for i := 0; ; i++ {
+			produced.Add(1)
+			task := NewProgressTask(func(n int) error {
+				if n == 4 {
+					return errFail
+				}
+				return nil
+			}, i).Weight(2)
+			if !yield(task) {
+				return
+			}
+		}
+	}
+	bar := NewProgressBar().Options(quietOptions()).Create()
+	if err := bar.RunSeq(context.Background(), seq); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if produced.Load() > 7 {
+		t.Errorf("expected iterator to stop after the failure, produced %d", produced.Load())
+	}
+
+	finite := NewProgressBar().Options(quietOptions()).Create()
+	err := finite.RunSeq(context.Background(), func(yield func(ProgressTask) bool) {
+		for i := 0; i < 5 && yield(NewProgressTask(func() {}).Weight(2)); i++ {
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := finite.State(); s.CurrentNum != 10 || s.Max != 10 || !finite.IsFinished() {
+		t.Errorf("expected finished bar at 10/10, got %d/%d", s.CurrentNum, s.Max)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	errFail := errors.New("fail")
+	var (
+		mu     sync.Mutex
+		events []string
+		run    RunEvent
+	)
+	record := func(kind string) func(TaskEvent) {
+		return func(e TaskEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, fmt.Sprintf("%s %s %d %v", kind, e.ID, e.Attempt, e.Err))
+		}
+	}
+	var calls int
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		OnFailure(ContinueOnError).
+		OnTaskStart(record("start")).
+		OnTaskSuccess(record("success")).
+		OnTaskFailure(record("failure")).
+		OnRetry(record("retry")).
+		OnRunComplete(func(e RunEvent) { run = e }).
+		Tasks(
+			NewNamedProgressTask("flaky", func() error {
+				calls++
+				if calls == 1 {
+					return errFail
+				}
+				return nil
+			}).Retry(NewRetryPolicy(2)),
+			NewNamedProgressTask("broken", func() error { return errFail }),
+			NewNamedProgressTask("after", func() {}).DependsOn("broken"),
+		).
+		Create()
+	err := bar.AutoRun()
+	want := []string{
+		"start flaky 1 <nil>",
+		"retry flaky 2 fail",
+		"success flaky 2 <nil>",
+		"start broken 1 <nil>",
+		"failure broken 1 fail",
+		"failure after 0 go-progressbar:task skipped because a dependency failed: broken",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("unexpected hook calls:\n%v\nwant:\n%v", events, want)
+	}
+	if run.Tasks != 3 || run.Succeeded != 1 || run.Failed != 2 || run.Err != err || run.Elapsed <= 0 {
+		t.Errorf("unexpected run event: %+v", run)
+	}
+}
+
+func TestSkippedTasks(t *testing.T) {
+	var ran, skipped []string
+	var run RunEvent
+	bar := NewProgressBar().
+		Options(quietOptions()).
+		OnRunComplete(func(e RunEvent) { run = e }).
+		OnTaskSkip(func(e TaskEvent) { skipped = append(skipped, e.Name) }).
+		Tasks(
+			NewNamedProgressTask("always", func() { ran = append(ran, "always") }),
+			NewNamedProgressTask("prod-only", func() { ran = append(ran, "prod-only") }).
+				SkipIf(func() bool { return true }).Weight(3),
+			NewNamedProgressTask("maybe", func() error { return ErrSkipTask }),
+			NewNamedProgressTask("after", func() { ran = append(ran, "after") }).DependsOn("prod-only", "maybe"),
+		).
+		Create()
+	results, err := bar.RunCollect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ran) != "[always after]" {
+		t.Errorf("unexpected tasks ran: %v", ran)
+	}
+	if !results[1].Skipped || results[1].Attempts != 0 || !results[2].Skipped || results[2].Err != nil {
+		t.Errorf("expected tasks to be skipped: %+v", results)
+	}
+	want := Summary{Succeeded: 2, Skipped: 2}
+	if bar.Summary() != want || run.Summary != want {
+		t.Errorf("expected summary %+v, got %+v and %+v", want, bar.Summary(), run.Summary)
+	}
+	var state struct {
+		CurrentNum int64
+		Skipped    int
+	}
+	if err := json.Unmarshal([]byte(bar.JSON()), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Skipped != 2 || state.CurrentNum != 6 {
+		t.Errorf("unexpected JSON state: %s", bar.JSON())
+	}
+	if fmt.Sprint(skipped) != "[prod-only maybe]" {
+		t.Errorf("expected a skip hook for every skipped task, got %v", skipped)
+	}
+
+	panicking := NewProgressBar().
+		Options(quietOptions()).
+		OnFailure(ContinueOnError).
+		Tasks(NewProgressTask(func() {}).SkipIf(func() bool { panic("boom") }), NewProgressTask(func() {})).
+		Create()
+	results, err = panicking.RunCollect(context.Background())
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || results[0].Skipped {
+		t.Errorf("expected a panicking predicate to fail the task, got %v", err)
+	}
+	if panicking.Summary().Succeeded != 1 {
+		t.Errorf("expected the other task to run, got %+v", panicking.Summary())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tasks := make([]ProgressTask, 0, 8)
+	for i := 0; i < 8; i++ {
+		tasks = append(tasks, NewProgressTask(func() {}))
+	}
+	var run RunEvent
+	out := &syncBuffer{}
+	bar := NewProgressBar().
+		Options(ProgressOptions().Writer(out)).
+		Concurrency(4).
+		RateLimit(NewRateLimiter(50, 2)).
+		OnRunComplete(func(e RunEvent) { run = e }).
+		Tasks(tasks...).
+		Create()
+	start := time.Now()
+	results, err := bar.RunCollect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 6 paced tasks to take at least 100ms, took %s", elapsed)
+	}
+	var waited time.Duration
+	for _, res := range results {
+		waited += res.Waited
+		if res.Duration > 20*time.Millisecond {
+			t.Errorf("expected waiting not to count as working time, got %s", res.Duration)
+		}
+	}
+	if waited == 0 || run.Waited != waited {
+		t.Errorf("expected waiting time to be reported, got %s and %s", waited, run.Waited)
+	}
+	if !strings.Contains(out.String(), "rate limited, waiting") {
+		t.Error("expected the bar to show waiting for the rate limiter")
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	if d, err := limiter.Wait(context.Background()); err != nil || d != 0 {
+		t.Fatalf("expected burst token to be free, got %s %v", d, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	errFail := errors.New("fail")
+	store := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	calls := make([]int, 5)
+	broken := true
+	newBar := func() *ProgressBar {
+		tasks := make([]ProgressTask, 0, 5)
+		for i := 0; i < 5; i++ {
+			tasks = append(tasks, NewProgressTask(func(n int) error {
+				calls[n]++
+				if n == 3 && broken {
+					return errFail
+				}
+				return nil
+			}, i))
+		}
+		return NewProgressBar().Options(quietOptions()).Checkpoint(store).Tasks(tasks...).Create()
+	}
+
+	if err := newBar().AutoRun(); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	checkpoint, err := store.Load()
+	if err != nil || checkpoint == nil {
+		t.Fatalf("expected a saved checkpoint, got %v %v", checkpoint, err)
+	}
+	if fmt.Sprint(checkpoint.Completed) != "[#0 #1 #2]" || checkpoint.State.CurrentNum != 3 {
+		t.Errorf("unexpected checkpoint: %+v", checkpoint)
+	}
+
+	broken = false
+	bar := newBar()
+	var before int64
+	bar.OnTaskStart(func(TaskEvent) {
+		if before == 0 {
+			before = bar.State().CurrentNum
+		}
+	})
+	results, err := bar.RunCollect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(calls) != "[1 1 1 2 1]" {
+		t.Errorf("expected finished tasks not to run again, calls: %v", calls)
+	}
+	if before != 3 || !results[0].Resumed || results[3].Resumed || bar.Summary().Resumed != 3 {
+		t.Errorf("expected bar to resume at 3, got %d: %+v", before, results)
+	}
+	if !bar.IsFinished() {
+		t.Error("expected bar to be finished")
+	}
+	if checkpoint, err := store.Load(); err != nil || checkpoint != nil {
+		t.Errorf("expected checkpoint to be cleared, got %+v %v", checkpoint, err)
+	}
+
+	// 继续执行时失败的任务不能记为已完成
+	calls = make([]int, 5)
+	broken = true
+	if err := newBar().OnFailure(ContinueOnError).AutoRun(); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if checkpoint, err := store.Load(); err != nil || fmt.Sprint(checkpoint.Completed) != "[#0 #1 #2 #4]" {
+		t.Fatalf("expected the failed task to be left out, got %+v %v", checkpoint, err)
+	}
+	broken = false
+	results, err = newBar().RunCollect(context.Background())
+	if err != nil || results[3].Resumed || fmt.Sprint(calls) != "[1 1 1 2 1]" {
+		t.Errorf("expected only the failed task to run again, got %v, calls %v", err, calls)
+	}
+}
+
+// memoryCheckpoint is a CheckpointStore counting its saves
+type memoryCheckpoint struct {
+	saved      *Checkpoint
+	saves      int
+	lastLength int
+}
+
+func (m *memoryCheckpoint) Load() (*Checkpoint, error) { return m.saved, nil }
+
+func (m *memoryCheckpoint) Save(checkpoint *Checkpoint) error {
+	m.saved = checkpoint
+	m.saves++
+	m.lastLength = len(checkpoint.Completed)
+	return nil
+}
+
+func (m *memoryCheckpoint) Clear() error {
+	m.saved = nil
+	return nil
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	errFail := errors.New("fail")
+	newTasks := func() []ProgressTask {
+		tasks := make([]ProgressTask, 0, 1000)
+		for i := 0; i < 1000; i++ {
+			tasks = append(tasks, NewProgressTask(func(n int) error {
+				if n == 999 {
+					return errFail
+				}
+				return nil
+			}, i))
+		}
+		return tasks
+	}
+
+	store := &memoryCheckpoint{}
+	bar := NewProgressBar().Options(quietOptions()).Checkpoint(store).OnFailure(ContinueOnError).Tasks(newTasks()...).Create()
+	if err := bar.AutoRun(); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if store.saves > 3 {
+		t.Errorf("expected the saves to be throttled, got %d", store.saves)
+	}
+	if store.lastLength != 999 {
+		t.Errorf("expected the last save to hold every finished task, got %d", store.lastLength)
+	}
+
+	store = &memoryCheckpoint{}
+	bar = NewProgressBar().Options(quietOptions()).Checkpoint(store).CheckpointInterval(0).OnFailure(ContinueOnError).Tasks(newTasks()...).Create()
+	if err := bar.AutoRun(); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if store.saves != 999 {
+		t.Errorf("expected a save after every finished task, got %d", store.saves)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var called atomic.Bool
+	call := func() { called.Store(true) }
+	var completed atomic.Bool
+	sim := &syncBuffer{}
+	bar := NewProgressBar().
+		Options(ProgressOptions().Writer(sim).Completion(func() { completed.Store(true) })).
+		Tasks(
+			NewNamedProgressTask("deploy", call).DependsOn("build", "test").Weight(2),
+			NewNamedProgressTask("build", func(s string) { called.Store(true) }, "x").Weight(5),
+			NewNamedProgressTask("test", call).DependsOn("build"),
+			NewProgressTask(func(ctx context.Context, n int) { called.Store(true) }, 1),
+		).
+		Create()
+	var out strings.Builder
+	if err := bar.DryRun(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "1. build (weight 5)\n2. #3 (weight 1)\n3. test (weight 1) after build\n4. deploy (weight 2) after build, test\n"
+	if out.String() != want {
+		t.Errorf("unexpected plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if called.Load() {
+		t.Error("expected no task to be called")
+	}
+	if !strings.Contains(sim.String(), "deploy") {
+		t.Error("expected the simulated run to be shown")
+	}
+	if s := bar.State(); s.CurrentNum != 0 || s.Max != 9 || completed.Load() {
+		t.Errorf("expected the bar to be left untouched, got %d/%d", s.CurrentNum, s.Max)
+	}
+	if err := bar.AutoRun(); err != nil || !called.Load() || !completed.Load() {
+		t.Errorf("expected the real run to follow the preview, got %v", err)
+	}
+
+	invalid := NewProgressBar().
+		Options(quietOptions()).
+		Tasks(
+			NewNamedProgressTask("ok", call),
+			NewNamedProgressTask("wrong type", func(int) {}, "1"),
+			NewNamedProgressTask("wrong count", func() {}, 1),
+		)
+	err := invalid.DryRun(io.Discard)
+	if !errors.Is(err, ErrInvalidTask) || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("expected two invalid tasks, got %v", err)
+	}
+	if !errors.Is(invalid.Create().Error(), ErrInvalidTask) {
+		t.Errorf("expected Create to reject the invalid tasks, got %v", invalid.Error())
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	exited := make(chan int, 1)
+	osExit = func(code int) { exited <- code }
+	defer func() { osExit = os.Exit }()
+
+	interrupt := func() {
+		self, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := self.Signal(os.Interrupt); err != nil {
+			t.Skip("sending signals is not supported:", err)
+		}
+	}
+	var ran atomic.Int32
+	tasks := make([]ProgressTask, 0, 5)
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, NewProgressTask(func(n int) {
+			ran.Add(1)
+			if n == 1 {
+				interrupt()
+				time.Sleep(50 * time.Millisecond)
+				interrupt()
+				select {
+				case <-exited:
+				case <-time.After(time.Second):
+					t.Error("expected second signal to force an exit")
+				}
+			}
+		}, i))
+	}
+	bar := NewProgressBar().Options(quietOptions()).HandleSignals().Tasks(tasks...).Create()
+	err := bar.AutoRun()
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if ran.Load() != 2 {
+		t.Errorf("expected the running task to finish and no new task to start, ran %d", ran.Load())
+	}
+	if bar.IsCancelled() {
+		t.Error("expected an interrupted bar not to count as cancelled")
+	}
+}
@@ -0,0 +1,62 @@
+package progressbar
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// osExit is replaced in tests
+var osExit = os.Exit
+
+// HandleSignals makes runs stop gracefully on one of sigs, SIGINT and SIGTERM
+// if none are given. The first signal stops scheduling new tasks and lets the
+// running ones finish, after which the bar is exited and the run returns
+// ErrInterrupted. A second signal exits the bar and the process at once.
+func (p *ProgressBar) HandleSignals(sigs ...os.Signal) *ProgressBar {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	p.signals = sigs
+	return p
+}
+
+// watchSignals interrupts the run on the signals set by HandleSignals until
+// the returned function is called
+func (r *run) watchSignals() func() {
+	if len(r.p.signals) == 0 {
+		return func() {}
+	}
+	sigs := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signal.Notify(sigs, r.p.signals...)
+	go func() {
+		select {
+		case <-sigs:
+			r.interrupt()
+		case <-done:
+			return
+		}
+		select {
+		case <-sigs:
+			_ = r.p.Exit()
+			osExit(130)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
+
+// interrupt stops scheduling new tasks of the run
+func (r *run) interrupt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.interrupted {
+		r.interrupted = true
+		r.stopped = true
+		close(r.halt)
+	}
+}
@@ -0,0 +1,112 @@
+package progressbar
+
+import "context"
+
+// RunStream executes the tasks received from tasks until it is closed,
+// following the same rules as AutoRunContext. Registered tasks are not run
+// and dependencies between streamed tasks are ignored.
+//
+// Unless Total was set the bar shows a spinner until ReportTotal is called
+// or tasks is closed, since the number of tasks is not known up front.
+func (p *ProgressBar) RunStream(ctx context.Context, tasks <-chan ProgressTask) error {
+	if p.Error() != nil {
+		return p.Error()
+	}
+	if p.bar.Load() == nil {
+		p.appendErr("RunStream", ErrNilBar)
+		return p.Error()
+	}
+
+	r := p.newRun(ctx)
+	defer r.watchSignals()()
+	if !p.totalSet {
+		r.indeterminate = true
+		p.changeMax(-1)
+	}
+	p.mu.Lock()
+	p.stream = r
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.stream = nil
+		p.mu.Unlock()
+	}()
+
+	r.schedule(nil, tasks, nil)
+	if !r.isStopped() {
+		r.setTotal(-1)
+	}
+	return r.finish()
+}
+
+// RunSeq is like RunStream but pulls the tasks from an iterator, such as an
+// iter.Seq[ProgressTask]. The iterator is stopped once the run stops early.
+func (p *ProgressBar) RunSeq(ctx context.Context, seq func(yield func(ProgressTask) bool)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tasks := make(chan ProgressTask)
+	go func() {
+		defer close(tasks)
+		seq(func(task ProgressTask) bool {
+			select {
+			case tasks <- task:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return p.RunStream(ctx, tasks)
+}
+
+// ReportTotal sets the total of the bar once it is known, switching a
+// running RunStream from its spinner to a regular bar that keeps the
+// progress made so far
+func (p *ProgressBar) ReportTotal(total int) error {
+	if p.bar.Load() == nil {
+		p.appendErr("ReportTotal", ErrNilBar)
+		return p.Error()
+	}
+	if total < 1 {
+		return ErrInvalidTotal
+	}
+	p.mu.Lock()
+	stream := p.stream
+	p.mu.Unlock()
+	if stream == nil {
+		p.changeMax(total)
+		return nil
+	}
+	stream.setTotal(total)
+	return nil
+}
+
+// setTotal switches the bar to the given total, or to the progress made so
+// far if total is negative, and restores the progress made so far
+func (r *run) setTotal(total int) {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+	if total < 0 {
+		if !r.indeterminate {
+			return
+		}
+		total = r.advanced
+	}
+	r.indeterminate = false
+	// 先清零再切换, 避免 spinner 的计数超过新的总数而直接结束
+	r.p.barMu.Lock()
+	bar := r.p.bar.Load()
+	err := bar.Add(-int(bar.State().CurrentNum))
+	bar.ChangeMax(total)
+	if err == nil {
+		err = bar.Add(r.advanced)
+	}
+	r.p.barMu.Unlock()
+	if err != nil {
+		r.mu.Lock()
+		if r.barErr == nil {
+			r.barErr = err
+		}
+		r.mu.Unlock()
+	}
+}
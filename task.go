@@ -0,0 +1,178 @@
+package progressbar
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+type ProgressTask struct {
+	id        string
+	dependsOn []string
+	name      string
+	fn        any
+	params    []any
+	call      func(ctx context.Context) ([]any, error)
+	retry     *RetryPolicy
+	timeout   time.Duration
+	weight    int
+	skipIf    func() bool
+}
+
+func NewProgressTask(fn any, params ...any) ProgressTask {
+	return ProgressTask{
+		fn:     fn,
+		params: params,
+	}
+}
+
+// NewFuncTask returns a task calling fn
+func NewFuncTask(fn func() error) ProgressTask {
+	return ProgressTask{
+		call: func(context.Context) ([]any, error) {
+			return nil, fn()
+		},
+	}
+}
+
+// NewFuncTask1 returns a task calling fn with arg, checked at compile time
+// unlike NewProgressTask
+func NewFuncTask1[T any](fn func(T) error, arg T) ProgressTask {
+	return ProgressTask{
+		call: func(context.Context) ([]any, error) {
+			return nil, fn(arg)
+		},
+	}
+}
+
+// NewContextTask returns a task calling fn with the context of the run
+func NewContextTask(fn func(context.Context) error) ProgressTask {
+	return ProgressTask{
+		call: func(ctx context.Context) ([]any, error) {
+			return nil, fn(ctx)
+		},
+	}
+}
+
+// NewContextTask1 returns a task calling fn with the context of the run
+// and arg. The returned value is reported in TaskResult.Values.
+func NewContextTask1[T, R any](fn func(context.Context, T) (R, error), arg T) ProgressTask {
+	return ProgressTask{
+		call: func(ctx context.Context) ([]any, error) {
+			value, err := fn(ctx, arg)
+			return []any{value}, err
+		},
+	}
+}
+
+// NewNamedProgressTask returns a task whose name is shown as the bar
+// description while AutoRun executes it
+func NewNamedProgressTask(name string, fn any, params ...any) ProgressTask {
+	return NewProgressTask(fn, params...).Named(name)
+}
+
+// Named returns a copy of the task with the given name
+func (t ProgressTask) Named(name string) ProgressTask {
+	t.name = name
+	return t
+}
+
+// Retry returns a copy of the task that is retried according to policy,
+// overriding the default policy of the bar
+func (t ProgressTask) Retry(policy *RetryPolicy) ProgressTask {
+	t.retry = policy
+	return t
+}
+
+// Timeout returns a copy of the task whose attempts fail with ErrTaskTimeout
+// once they take longer than timeout. Task functions accepting a
+// context.Context see it cancelled at the same time.
+func (t ProgressTask) Timeout(timeout time.Duration) ProgressTask {
+	t.timeout = timeout
+	return t
+}
+
+// Weight returns a copy of the task that advances the bar by weight instead
+// of 1 once it is done, so long running tasks take a larger share of the bar
+func (t ProgressTask) Weight(weight int) ProgressTask {
+	t.weight = weight
+	return t
+}
+
+// weightOrDefault returns the weight of the task, at least 1
+func (t ProgressTask) weightOrDefault() int {
+	if t.weight < 1 {
+		return 1
+	}
+	return t.weight
+}
+
+// ID returns a copy of the task with an id other tasks can depend on.
+// Ids must be unique. Tasks without an id can be depended on by their name
+// as long as no other task has the same name.
+func (t ProgressTask) ID(id string) ProgressTask {
+	t.id = id
+	return t
+}
+
+// DependsOn returns a copy of the task that only starts once the tasks with
+// the given ids succeeded. It is skipped if one of them fails.
+func (t ProgressTask) DependsOn(ids ...string) ProgressTask {
+	t.dependsOn = append(append([]string(nil), t.dependsOn...), ids...)
+	return t
+}
+
+// invoke calls the function of the task, converting a panic into a *PanicError
+func (t ProgressTask) invoke(ctx context.Context) (values []any, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			values, err = nil, &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	if t.call != nil {
+		return t.call(ctx)
+	}
+	return callFunc(ctx, t.fn, t.params...)
+}
+
+// skipped evaluates the SkipIf predicate of the task, converting a panic
+// into a *PanicError
+func (t ProgressTask) skipped() (skip bool, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			skip, err = false, &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return t.skipIf != nil && t.skipIf(), nil
+}
+
+// validate checks that the parameters of a task created by NewProgressTask
+// match its function, without calling it
+func (t ProgressTask) validate() error {
+	if t.call != nil || t.fn == nil {
+		return nil
+	}
+	f := reflect.ValueOf(t.fn)
+	if f.IsZero() {
+		return nil
+	}
+	_, err := funcArgs(context.Background(), f, t.params)
+	return err
+}
+
+// key returns the id of the task, or its name if it has none
+func (t ProgressTask) key() string {
+	if t.id != "" {
+		return t.id
+	}
+	return t.name
+}
+
+// SkipIf returns a copy of the task that is skipped without being called when
+// fn returns true at the time the task is due. Skipped tasks still advance
+// the bar and are counted separately in the Summary.
+func (t ProgressTask) SkipIf(fn func() bool) ProgressTask {
+	t.skipIf = fn
+	return t
+}
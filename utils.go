@@ -1,31 +1,140 @@
 package progressbar
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 )
 
-func callFunc(fn any, params ...any) error {
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// callFunc calls fn with params. If the first parameter of fn is a
+// context.Context that is not part of params, ctx is passed in its place.
+// Return values whose type implements error are reported as error, the
+// first non-nil one wins, all others as values.
+func callFunc(ctx context.Context, fn any, params ...any) ([]any, error) {
 	if fn == nil {
-		return nil
+		return nil, nil
 	}
 	f := reflect.ValueOf(fn)
 	if f.IsZero() {
-		return nil
+		return nil, nil
+	}
+	in, err := funcArgs(ctx, f, params)
+	if err != nil {
+		return nil, err
+	}
+	var returnValues []reflect.Value
+	if f.Type().IsVariadic() {
+		returnValues = f.CallSlice(in)
+	} else {
+		returnValues = f.Call(in)
+	}
+	var values []any
+	for k, val := range returnValues {
+		if !f.Type().Out(k).Implements(errorType) {
+			values = append(values, val.Interface())
+			continue
+		}
+		// 返回 nil 指针的具体错误类型不算失败
+		if err == nil && !(nillable(val.Type()) && val.IsNil()) {
+			err = val.Interface().(error)
+		}
+	}
+	return values, err
+}
+
+// funcArgs checks that params match the signature of f and converts them to
+// call arguments, so that a mismatch is reported instead of panicking. The
+// arguments of a variadic function are meant for CallSlice: the trailing
+// params are packed into a slice, unless there is exactly one and it
+// already is a slice of the variadic type.
+func funcArgs(ctx context.Context, f reflect.Value, params []any) ([]reflect.Value, error) {
+	if f.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%w: %s is not a function", ErrInvalidTask, f.Type())
+	}
+	fnType := f.Type()
+	fixed := fnType.NumIn()
+	if fnType.IsVariadic() {
+		fixed--
+	}
+	if wantsContext(fnType) && contextMissing(fnType, params) {
+		params = append([]any{ctx}, params...)
+	}
+	switch {
+	case fnType.IsVariadic() && len(params) < fixed:
+		return nil, fmt.Errorf("%w: variadic function takes at least %d parameters, got %d", ErrInvalidTask, fixed, len(params))
+	case !fnType.IsVariadic() && len(params) != fixed:
+		return nil, fmt.Errorf("%w: function takes %d parameters, got %d", ErrInvalidTask, fixed, len(params))
+	}
+	in := make([]reflect.Value, fixed, fnType.NumIn())
+	for k := 0; k < fixed; k++ {
+		arg, err := funcArg(k, params[k], fnType.In(k))
+		if err != nil {
+			return nil, err
+		}
+		in[k] = arg
 	}
-	if len(params) != f.Type().NumIn() {
-		return fmt.Errorf("expected function with %d parameters, got one with %d", f.Type().NumIn(), len(params))
+	if !fnType.IsVariadic() {
+		return in, nil
 	}
-	in := make([]reflect.Value, len(params))
-	for k, param := range params {
-		in[k] = reflect.ValueOf(param)
+
+	rest, sliceType := params[fixed:], fnType.In(fixed)
+	if len(rest) == 1 && rest[0] != nil && reflect.TypeOf(rest[0]).AssignableTo(sliceType) {
+		return append(in, reflect.ValueOf(rest[0])), nil
+	}
+	slice := reflect.MakeSlice(sliceType, len(rest), len(rest))
+	for i, param := range rest {
+		arg, err := funcArg(fixed+i, param, sliceType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		slice.Index(i).Set(arg)
 	}
-	returnValues := f.Call(in)
-	for _, val := range returnValues {
-		i := val.Interface()
-		if err, ok := i.(error); ok {
-			return err
+	return append(in, slice), nil
+}
+
+// funcArg converts the parameter at position k to a call argument of type want
+func funcArg(k int, param any, want reflect.Type) (reflect.Value, error) {
+	if param == nil {
+		if nillable(want) {
+			return reflect.Zero(want), nil
 		}
+		return reflect.Value{}, fmt.Errorf("%w: parameter %d is nil, expected %s", ErrInvalidTask, k, want)
+	}
+	arg := reflect.ValueOf(param)
+	if !arg.Type().AssignableTo(want) {
+		return reflect.Value{}, fmt.Errorf("%w: parameter %d is %s, expected %s", ErrInvalidTask, k, arg.Type(), want)
+	}
+	return arg, nil
+}
+
+// nillable reports whether values of type t can be nil
+func nillable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+// wantsContext reports whether the first parameter of fn is a context.Context
+func wantsContext(fn reflect.Type) bool {
+	return fn.NumIn() > 0 && fn.In(0) == contextType
+}
+
+// contextMissing reports whether params leave out the context.Context that
+// fn takes first
+func contextMissing(fn reflect.Type, params []any) bool {
+	if !fn.IsVariadic() {
+		return len(params) == fn.NumIn()-1
+	}
+	if len(params) == 0 {
+		return true
 	}
-	return nil
+	_, ok := params[0].(context.Context)
+	return !ok
 }